@@ -6,31 +6,33 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"github.com/Seann-Moser/cutil/logc"
-	cache "github.com/patrickmn/go-cache"
 	"reflect"
 	"strings"
 	"sync"
 	"time"
-
-	"go.uber.org/zap"
 )
 
 const (
 	CTX_CACHE = "cache_ctx"
+
+	// NoExpiration is returned by TTL for entries that never expire.
+	NoExpiration time.Duration = -1
 )
 
 var (
 	ErrCacheMiss    = errors.New("cache missed")
 	ErrCacheUpdated = errors.New("cache updated")
-	DefaultCache    Cache
-	SyncMutex       = sync.RWMutex{}
+	ErrNotSupported = errors.New("operation not supported by cache")
+	// DefaultCache is used when the context has no cache, a process wide GoCache is used while it is nil.
+	DefaultCache Cache
+	// Deprecated: SyncMutex is not used by the package.
+	SyncMutex = sync.RWMutex{}
 )
 
 type Cache interface {
 	SetCache
 	GetCache
+	ExpireCache
 	DeleteKey(ctx context.Context, key string) error
 	Ping(ctx context.Context) error
 	Close()
@@ -47,6 +49,39 @@ type GetCache interface {
 	GetCache(ctx context.Context, group, key string) ([]byte, error)
 }
 
+// ExpireCache inspects and changes the expiration of existing entries without rewriting them.
+// A ttl <= 0 passed to Touch uses the cache default duration.
+type ExpireCache interface {
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+	Persist(ctx context.Context, key string) error
+}
+
+// GroupCache is an optional capability of caches that can list and delete a group without the monitor's key registry.
+// It only sees the entries written while GroupKeyLayout reports true, the client then starts keys with their group.
+type GroupCache interface {
+	GroupKeyLayout() bool
+	GroupKeys(ctx context.Context, group string) ([]string, error)
+	DeleteGroup(ctx context.Context, group string) (int, error)
+}
+
+var groupTagReplacer = strings.NewReplacer(`\`, `\\`, `}`, `\}`)
+
+// GroupKeyPrefix returns the prefix of the keys of group in the group key layout. The group is escaped into a
+// redis hash tag, so no other group's keys share the prefix and a group is kept in one cluster slot.
+func GroupKeyPrefix(group string) string {
+	return "{" + groupTagReplacer.Replace(group) + "}:"
+}
+
+// groupCache returns the cache as a GroupCache when it uses the group key layout.
+func groupCache(cache Cache) (GroupCache, bool) {
+	gc, ok := cache.(GroupCache)
+	if !ok || !gc.GroupKeyLayout() {
+		return nil, false
+	}
+	return gc, true
+}
+
 func getType(myVar interface{}) string {
 	if myVar == nil {
 		return "nil"
@@ -65,29 +100,35 @@ func getType(myVar interface{}) string {
 	}
 }
 
+// encodeItem stores raw bytes as is, they are treated as an already encoded value.
+func encodeItem(item interface{}) ([]byte, error) {
+	if b, ok := item.([]byte); ok {
+		return b, nil
+	}
+	return json.Marshal(item)
+}
+
 func GetMD5Hash(text string) string {
 	hash := md5.Sum([]byte(text))
 	return base64.StdEncoding.EncodeToString(hash[:])
 }
 
+// GetKey returns the key without a tenant, use GetKeyCtx for keys that honour the tenant in the context.
 func GetKey[T any](key ...string) string {
-	var d T
-	return GetMD5Hash(fmt.Sprintf("%s_%s", getType(d), strings.Join(key, "_")))
+	return Default().Key(typeName[T](), key...)
+}
+
+func GetKeyCtx[T any](ctx context.Context, key ...string) (string, error) {
+	return Default().KeyCtx(ctx, typeName[T](), key...)
 }
 
 func Set[T any](ctx context.Context, group, key string, data T) error {
-	err := GetCacheFromContext(ctx).SetCache(ctx, group, GetKey[T](group, key), Wrapper[T]{Data: data})
-	if err != nil {
-		return err
-	}
-	if strings.EqualFold(group, GroupPrefix) {
-		return nil
-	}
-	return GlobalCacheMonitor.UpdateCache(ctx, group, key)
+	c := Default()
+	return c.set(ctx, c.Cache(ctx), typeName[T](), group, key, data, 0, false)
 }
 
 func Delete[T any](ctx context.Context, group, key string) error {
-	return GetCacheFromContext(ctx).DeleteKey(ctx, GetKey[T](group, key))
+	return Default().delete(ctx, typeName[T](), group, key)
 }
 
 func DeleteKey(ctx context.Context, key string) error {
@@ -95,23 +136,31 @@ func DeleteKey(ctx context.Context, key string) error {
 }
 
 func SetWithExpiration[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, data T) error {
-	err := GetCacheFromContext(ctx).SetCacheWithExpiration(ctx, cacheTimeout, group, GetKey[T](group, key), Wrapper[T]{Data: data})
+	c := Default()
+	return c.set(ctx, c.Cache(ctx), typeName[T](), group, key, data, cacheTimeout, true)
+}
+
+func SetFromCache[T any](ctx context.Context, cache Cache, group, key string, data T) error {
+	item, err := Default().encode(data)
 	if err != nil {
-		logc.Debug(ctx, "failed setting cache", zap.String("group", group), zap.String("key", key))
 		return err
 	}
-	if strings.EqualFold(group, GroupPrefix) {
-		return nil
+	k, err := Default().groupKey(ctx, cache, typeName[T](), group, key)
+	if err != nil {
+		return err
 	}
-	logc.Debug(ctx, "set cache", zap.String("group", group), zap.String("key", key))
-	return GlobalCacheMonitor.UpdateCache(ctx, group, key)
-}
-
-func SetFromCache[T any](ctx context.Context, cache Cache, group, key string, data T) error {
-	return cache.SetCache(ctx, group, GetKey[T](group, key), Wrapper[T]{Data: data})
+	return cache.SetCache(ctx, group, k, item)
 }
 func SetFromCacheWithExpiration[T any](ctx context.Context, cache Cache, cacheTimeout time.Duration, group, key string, data T) error {
-	return cache.SetCacheWithExpiration(ctx, cacheTimeout, group, GetKey[T](group, key), Wrapper[T]{Data: data})
+	item, err := Default().encode(data)
+	if err != nil {
+		return err
+	}
+	k, err := Default().groupKey(ctx, cache, typeName[T](), group, key)
+	if err != nil {
+		return err
+	}
+	return cache.SetCacheWithExpiration(ctx, cacheTimeout, group, k, item)
 }
 
 type Wrapper[T any] struct {
@@ -119,27 +168,39 @@ type Wrapper[T any] struct {
 }
 
 func Get[T any](ctx context.Context, group, key string) (*T, error) {
-	if group != "" && GlobalCacheMonitor.HasGroupKeyBeenUpdated(ctx, group) {
-		logc.Debug(ctx, "group has been updated", zap.String("group", group), zap.String("key", key))
-		return nil, ErrCacheUpdated
-	}
-	data, err := GetCacheFromContext(ctx).GetCache(ctx, group, GetKey[T](group, key))
+	c := Default()
+	var output T
+	err := c.get(ctx, c.Cache(ctx), typeName[T](), group, key, &output)
 	if err != nil {
 		return nil, err
 	}
+	return &output, nil
+}
 
-	var output Wrapper[T]
-	err = json.Unmarshal(data, &output)
+// GetWithTTL returns the value with its remaining time to live, the value is returned even if the TTL can not be read.
+func GetWithTTL[T any](ctx context.Context, group, key string) (*T, time.Duration, error) {
+	v, err := Get[T](ctx, group, key)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	cache := GetCacheFromContext(ctx)
+	k, err := Default().groupKey(ctx, cache, typeName[T](), group, key)
+	if err != nil {
+		return v, 0, err
 	}
-	logc.Debug(ctx, "using cache", zap.String("group", group), zap.String("key", key))
-	return &output.Data, nil
+	ttl, err := cache.TTL(ctx, k)
+	if err != nil {
+		return v, 0, err
+	}
+	return v, ttl, nil
 }
 
 func GetSet[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, gtr func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := startGetSet(ctx, "GetSet", group)
+	defer span.End()
 	if v, err := Get[T](ctx, group, key); errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil {
-		nv, err := gtr(ctx)
+		span.SetAttributes(AttrHit.Bool(false))
+		nv, err := load(ctx, group, gtr)
 		if err != nil {
 			var tmp T
 			return tmp, err
@@ -147,12 +208,16 @@ func GetSet[T any](ctx context.Context, cacheTimeout time.Duration, group, key s
 		_ = SetWithExpiration[T](ctx, cacheTimeout, group, key, nv)
 		return nv, nil
 	} else {
+		span.SetAttributes(AttrHit.Bool(true))
 		return *v, nil
 	}
 }
 func GetSetP[T any](ctx context.Context, cacheTimeout time.Duration, group, key string, gtr func(ctx context.Context) (*T, error)) (*T, error) {
+	ctx, span := startGetSet(ctx, "GetSet", group)
+	defer span.End()
 	if v, err := Get[T](ctx, group, key); errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrCacheUpdated) || v == nil {
-		nv, err := gtr(ctx)
+		span.SetAttributes(AttrHit.Bool(false))
+		nv, err := load(ctx, group, gtr)
 		if err != nil {
 			return nil, err
 		}
@@ -162,24 +227,48 @@ func GetSetP[T any](ctx context.Context, cacheTimeout time.Duration, group, key
 		_ = SetWithExpiration[T](ctx, cacheTimeout, group, key, *nv)
 		return nv, nil
 	} else {
+		span.SetAttributes(AttrHit.Bool(true))
 		return v, nil
 	}
 }
 
 func GetFromCache[T any](ctx context.Context, cache Cache, group, key string) (*T, error) {
-	if GlobalCacheMonitor.HasGroupKeyBeenUpdated(ctx, group) {
+	if Default().Monitor().HasGroupKeyBeenUpdated(ctx, group) {
 		return nil, ErrCacheUpdated
 	}
-	data, err := cache.GetCache(ctx, group, GetKey[T](group, key))
+	c := Default()
+	k, err := c.groupKey(ctx, cache, typeName[T](), group, key)
 	if err != nil {
 		return nil, err
 	}
-	var output Wrapper[T]
-	err = json.Unmarshal(data, &output)
+	data, err := cache.GetCache(withLookup(ctx, c, typeName[T](), key), group, k)
 	if err != nil {
 		return nil, err
 	}
-	return &output.Data, nil
+	var output T
+	err = c.decode(data, &output)
+	if err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// Peek reads the entry without checking whether its group has been updated, for entries that are invalidated by key.
+func Peek[T any](ctx context.Context, cache Cache, group, key string) (*T, error) {
+	c := Default()
+	k, err := c.groupKey(ctx, cache, typeName[T](), group, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := cache.GetCache(withLookup(ctx, c, typeName[T](), key), group, k)
+	if err != nil {
+		return nil, err
+	}
+	var output T
+	if err := c.decode(data, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
 }
 
 func ContextWithCache(ctx context.Context, cache Cache) context.Context {
@@ -188,25 +277,10 @@ func ContextWithCache(ctx context.Context, cache Cache) context.Context {
 
 func GetCacheFromContext(ctx context.Context) Cache {
 	if ctx == nil {
-		if DefaultCache == nil {
-			DefaultCache = &GoCache{
-				defaultDuration: cache.DefaultExpiration,
-				cacher:          cache.New(5*time.Minute, time.Minute),
-				cacheTags:       NewCacheTags("go-cache", "backup"),
-			}
-		}
-		return DefaultCache
-	}
-	gCache := ctx.Value(CTX_CACHE)
-	if gCache == nil {
-		if DefaultCache == nil {
-			DefaultCache = &GoCache{
-				defaultDuration: cache.DefaultExpiration,
-				cacher:          cache.New(5*time.Minute, time.Minute),
-				cacheTags:       NewCacheTags("go-cache", "backup"),
-			}
-		}
-		return DefaultCache
+		return fallbackCache()
+	}
+	if c, ok := ctx.Value(CTX_CACHE).(Cache); ok && c != nil {
+		return c
 	}
-	return gCache.(Cache)
+	return fallbackCache()
 }
@@ -28,7 +28,7 @@ func TestTieredCache(t *testing.T) {
 			Cache:          NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""),
 			Key:            "test_cache",
 			Value:          "test",
-			ExpectedOutput: "test",
+			ExpectedOutput: `"test"`,
 			ExpectedErr:    nil,
 		},
 		{
@@ -53,7 +53,7 @@ func TestTieredCache(t *testing.T) {
 			Key:   "test_cache",
 			Value: "test",
 
-			ExpectedOutput: "test",
+			ExpectedOutput: `"test"`,
 		},
 	}
 	GlobalCacheMonitor = NewMonitor()
@@ -256,3 +256,18 @@ func TestDeleteKey(t *testing.T) {
 	assert.NoError(t, err)
 
 }
+
+func TestGetWithTTL(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	ctx := ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	err := SetWithExpiration[string](ctx, time.Minute, "", "ttl", "value")
+	assert.NoError(t, err)
+
+	v, ttl, err := GetWithTTL[string](ctx, "", "ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", *v)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+
+	_, _, err = GetWithTTL[string](ctx, "", "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
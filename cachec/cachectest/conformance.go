@@ -0,0 +1,207 @@
+package cachectest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+)
+
+// Factory returns a new, empty cache for a single conformance check.
+type Factory func(t *testing.T) cachec.Cache
+
+type conformanceItem struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// RunConformance checks that a Cache implementation follows the behaviour the cachec helpers rely on.
+func RunConformance(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("miss", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		_, err := c.GetCache(ctx, "conformance", "missing")
+		if !errors.Is(err, cachec.ErrCacheMiss) {
+			t.Errorf("expected ErrCacheMiss for a missing key, got: %v", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		checkRoundTrip(ctx, t, c, "string", "value")
+		checkRoundTrip(ctx, t, c, "int", 42)
+		checkRoundTrip(ctx, t, c, "bool", true)
+		checkRoundTrip(ctx, t, c, "map", map[string]int{"a": 1, "b": 2})
+		checkRoundTrip(ctx, t, c, "slice", []string{"a", "b"})
+		checkRoundTrip(ctx, t, c, "struct", conformanceItem{Name: "item", Count: 3, Tags: map[string]string{"k": "v"}})
+		checkRoundTrip(ctx, t, c, "wrapper", cachec.Wrapper[conformanceItem]{Data: conformanceItem{Name: "wrapped"}})
+	})
+
+	t.Run("raw bytes", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		raw := []byte(`{"data":"encoded"}`)
+		if err := c.SetCache(ctx, "conformance", "raw", raw); err != nil {
+			t.Fatalf("failed setting raw bytes: %s", err.Error())
+		}
+		data, err := c.GetCache(ctx, "conformance", "raw")
+		if err != nil {
+			t.Fatalf("failed getting raw bytes: %s", err.Error())
+		}
+		if string(data) != string(raw) {
+			t.Errorf("raw bytes were re-encoded: %s != %s", string(raw), string(data))
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		checkRoundTrip(ctx, t, c, "overwrite", "first")
+		checkRoundTrip(ctx, t, c, "overwrite", "second")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		if err := c.SetCache(ctx, "conformance", "delete", "value"); err != nil {
+			t.Fatalf("failed setting cache: %s", err.Error())
+		}
+		if err := c.DeleteKey(ctx, "delete"); err != nil {
+			t.Fatalf("failed deleting key: %s", err.Error())
+		}
+		if _, err := c.GetCache(ctx, "conformance", "delete"); !errors.Is(err, cachec.ErrCacheMiss) {
+			t.Errorf("expected ErrCacheMiss after delete, got: %v", err)
+		}
+		if err := c.DeleteKey(ctx, "delete"); err != nil {
+			t.Errorf("deleting a missing key should not fail: %s", err.Error())
+		}
+	})
+
+	t.Run("ttl expiry", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		if err := c.SetCacheWithExpiration(ctx, time.Second, "conformance", "expiring", "value"); err != nil {
+			t.Fatalf("failed setting cache: %s", err.Error())
+		}
+		if _, err := c.GetCache(ctx, "conformance", "expiring"); err != nil {
+			t.Fatalf("expected value before expiry: %s", err.Error())
+		}
+		time.Sleep(1500 * time.Millisecond)
+		if _, err := c.GetCache(ctx, "conformance", "expiring"); !errors.Is(err, cachec.ErrCacheMiss) {
+			t.Errorf("expected ErrCacheMiss after expiry, got: %v", err)
+		}
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		if err := c.Touch(ctx, "missing", time.Minute); !errors.Is(err, cachec.ErrCacheMiss) {
+			t.Errorf("expected ErrCacheMiss touching a missing key, got: %v", err)
+		}
+		if _, err := c.TTL(ctx, "missing"); !errors.Is(err, cachec.ErrCacheMiss) && !errors.Is(err, cachec.ErrNotSupported) {
+			t.Errorf("expected ErrCacheMiss for the ttl of a missing key, got: %v", err)
+		}
+		if err := c.SetCacheWithExpiration(ctx, time.Minute, "conformance", "touched", "value"); err != nil {
+			t.Fatalf("failed setting cache: %s", err.Error())
+		}
+		ttl, err := c.TTL(ctx, "touched")
+		if err != nil && !errors.Is(err, cachec.ErrNotSupported) {
+			t.Fatalf("failed getting ttl: %s", err.Error())
+		}
+		if err == nil && (ttl <= 0 || ttl > time.Minute) {
+			t.Errorf("ttl should be within the expiration: %s", ttl)
+		}
+		if err = c.Persist(ctx, "touched"); err != nil {
+			t.Fatalf("failed persisting key: %s", err.Error())
+		}
+		if ttl, err = c.TTL(ctx, "touched"); err == nil && ttl != cachec.NoExpiration {
+			t.Errorf("persisted key should not expire: %s", ttl)
+		}
+		if err = c.Touch(ctx, "touched", time.Second); err != nil {
+			t.Fatalf("failed touching key: %s", err.Error())
+		}
+		time.Sleep(1500 * time.Millisecond)
+		if _, err = c.GetCache(ctx, "conformance", "touched"); !errors.Is(err, cachec.ErrCacheMiss) {
+			t.Errorf("expected ErrCacheMiss after touched expiry, got: %v", err)
+		}
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := fmt.Sprintf("concurrent_%d", i)
+				for j := 0; j < 10; j++ {
+					if err := c.SetCache(ctx, "conformance", key, j); err != nil {
+						t.Errorf("failed setting %s: %s", key, err.Error())
+						return
+					}
+					data, err := c.GetCache(ctx, "conformance", key)
+					if err != nil {
+						t.Errorf("failed getting %s: %s", key, err.Error())
+						return
+					}
+					var v int
+					if err = json.Unmarshal(data, &v); err != nil || v != j {
+						t.Errorf("unexpected value for %s: %s", key, string(data))
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("ping", func(t *testing.T) {
+		c := factory(t)
+		defer c.Close()
+		if err := c.Ping(ctx); err != nil {
+			t.Errorf("ping failed: %s", err.Error())
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		c := factory(t)
+		if c.GetName() == "" {
+			t.Errorf("cache name should not be empty")
+		}
+		c.Close()
+		c.Close()
+	})
+}
+
+func checkRoundTrip[T any](ctx context.Context, t *testing.T, c cachec.Cache, key string, value T) {
+	t.Helper()
+	if err := c.SetCache(ctx, "conformance", key, value); err != nil {
+		t.Errorf("failed setting %s: %s", key, err.Error())
+		return
+	}
+	data, err := c.GetCache(ctx, "conformance", key)
+	if err != nil {
+		t.Errorf("failed getting %s: %s", key, err.Error())
+		return
+	}
+	var output T
+	if err = json.Unmarshal(data, &output); err != nil {
+		t.Errorf("value for %s is not json (%s): %s", key, string(data), err.Error())
+		return
+	}
+	expected, _ := json.Marshal(value)
+	actual, _ := json.Marshal(output)
+	if string(expected) != string(actual) {
+		t.Errorf("round trip mismatch for %s: %s != %s", key, string(expected), string(actual))
+	}
+}
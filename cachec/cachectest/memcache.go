@@ -0,0 +1,210 @@
+package cachectest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultMemcacheItemSize mirrors the default memcached item size limit.
+const DefaultMemcacheItemSize = 1024 * 1024
+
+// MemcacheServer is an in-process stand-in for memcached that speaks the text protocol.
+type MemcacheServer struct {
+	// MaxItemSize rejects larger values with SERVER_ERROR like memcached does.
+	MaxItemSize int
+
+	listener net.Listener
+	mutex    *sync.Mutex
+	items    map[string]memcacheItem
+	casID    uint64
+	wg       sync.WaitGroup
+}
+
+type memcacheItem struct {
+	value   []byte
+	flags   uint32
+	casID   uint64
+	expires time.Time
+}
+
+// StartMemcache starts a MemcacheServer on a random local port and stops it when the test ends.
+func StartMemcache(t testing.TB) *MemcacheServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting memcache stand-in: %s", err.Error())
+	}
+	s := &MemcacheServer{
+		MaxItemSize: DefaultMemcacheItemSize,
+		listener:    l,
+		mutex:       &sync.Mutex{},
+		items:       map[string]memcacheItem{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *MemcacheServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *MemcacheServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// Len returns the number of live items stored.
+func (s *MemcacheServer) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expire(time.Now())
+	return len(s.items)
+}
+
+func (s *MemcacheServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *MemcacheServer) expire(now time.Time) {
+	for k, v := range s.items {
+		if !v.expires.IsZero() && !now.Before(v.expires) {
+			delete(s.items, k)
+		}
+	}
+}
+
+func expiration(seconds int64, now time.Time) time.Time {
+	switch {
+	case seconds == 0:
+		return time.Time{}
+	case seconds < 0:
+		return now
+	case seconds > 60*60*24*30:
+		return time.Unix(seconds, 0)
+	}
+	return now.Add(time.Duration(seconds) * time.Second)
+}
+
+func (s *MemcacheServer) handle(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var value []byte
+		switch fields[0] {
+		case "set", "add", "replace", "cas":
+			if len(fields) < 5 {
+				_, _ = rw.WriteString("ERROR\r\n")
+				break
+			}
+			size, err := strconv.Atoi(fields[4])
+			if err != nil {
+				_, _ = rw.WriteString("CLIENT_ERROR bad data chunk\r\n")
+				break
+			}
+			value = make([]byte, size+2)
+			if _, err = io.ReadFull(rw.Reader, value); err != nil {
+				return
+			}
+			value = value[:size]
+		}
+		s.exec(rw.Writer, fields, value)
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *MemcacheServer) exec(w *bufio.Writer, fields []string, value []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	s.expire(now)
+	switch fields[0] {
+	case "get", "gets":
+		for _, k := range fields[1:] {
+			it, found := s.items[k]
+			if !found {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", k, it.flags, len(it.value), it.casID)
+			_, _ = w.Write(it.value)
+			_, _ = w.WriteString("\r\n")
+		}
+		_, _ = w.WriteString("END\r\n")
+	case "set", "add", "replace", "cas":
+		if len(value) > s.MaxItemSize {
+			_, _ = w.WriteString("SERVER_ERROR object too large for cache\r\n")
+			return
+		}
+		flags, _ := strconv.ParseUint(fields[2], 10, 32)
+		exp, _ := strconv.ParseInt(fields[3], 10, 64)
+		existing, found := s.items[fields[1]]
+		switch {
+		case fields[0] == "add" && found:
+			_, _ = w.WriteString("NOT_STORED\r\n")
+			return
+		case fields[0] == "replace" && !found:
+			_, _ = w.WriteString("NOT_STORED\r\n")
+			return
+		case fields[0] == "cas" && !found:
+			_, _ = w.WriteString("NOT_FOUND\r\n")
+			return
+		case fields[0] == "cas" && len(fields) > 5 && fields[5] != strconv.FormatUint(existing.casID, 10):
+			_, _ = w.WriteString("EXISTS\r\n")
+			return
+		}
+		s.casID++
+		s.items[fields[1]] = memcacheItem{
+			value:   value,
+			flags:   uint32(flags),
+			casID:   s.casID,
+			expires: expiration(exp, now),
+		}
+		_, _ = w.WriteString("STORED\r\n")
+	case "delete":
+		if _, found := s.items[fields[1]]; !found {
+			_, _ = w.WriteString("NOT_FOUND\r\n")
+			return
+		}
+		delete(s.items, fields[1])
+		_, _ = w.WriteString("DELETED\r\n")
+	case "touch":
+		it, found := s.items[fields[1]]
+		if !found || len(fields) < 3 {
+			_, _ = w.WriteString("NOT_FOUND\r\n")
+			return
+		}
+		exp, _ := strconv.ParseInt(fields[2], 10, 64)
+		it.expires = expiration(exp, now)
+		s.items[fields[1]] = it
+		_, _ = w.WriteString("TOUCHED\r\n")
+	case "flush_all":
+		s.items = map[string]memcacheItem{}
+		_, _ = w.WriteString("OK\r\n")
+	default:
+		_, _ = w.WriteString("ERROR\r\n")
+	}
+}
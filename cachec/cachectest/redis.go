@@ -0,0 +1,478 @@
+package cachectest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RedisServer is an in-process stand-in for redis that speaks enough RESP for the cachec backends.
+type RedisServer struct {
+	listener    net.Listener
+	mutex       *sync.Mutex
+	items       map[string]redisItem
+	subscribers map[string]map[*redisConn]struct{}
+	// cursors map SCAN cursors to the last key returned, so keys deleted between calls do not shift the scan.
+	cursors    map[int]string
+	nextCursor int
+	wg         sync.WaitGroup
+}
+
+type redisConn struct {
+	mutex      *sync.Mutex
+	w          *bufio.Writer
+	subscribed map[string]struct{}
+}
+
+type redisItem struct {
+	value   []byte
+	expires time.Time
+}
+
+func (i redisItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// StartRedis starts a RedisServer on a random local port and stops it when the test ends.
+func StartRedis(t testing.TB) *RedisServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed starting redis stand-in: %s", err.Error())
+	}
+	s := &RedisServer{
+		listener:    l,
+		mutex:       &sync.Mutex{},
+		items:       map[string]redisItem{},
+		subscribers: map[string]map[*redisConn]struct{}{},
+		cursors:     map[int]string{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *RedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *RedisServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// Keys returns the live keys currently stored.
+func (s *RedisServer) Keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	var keys []string
+	for k, v := range s.items {
+		if !v.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *RedisServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *RedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	rc := &redisConn{
+		mutex:      &sync.Mutex{},
+		w:          bufio.NewWriter(conn),
+		subscribed: map[string]struct{}{},
+	}
+	defer s.unsubscribe(rc)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		rc.mutex.Lock()
+		switch strings.ToLower(args[0]) {
+		case "subscribe":
+			s.subscribe(rc, args[1:]...)
+		case "publish":
+			if len(args) != 3 {
+				writeError(rc.w, "wrong number of arguments for 'publish' command")
+				break
+			}
+			writeInt(rc.w, s.publish(args[1], args[2]))
+		case "ping":
+			if len(rc.subscribed) > 0 {
+				writeArray(rc.w, []string{"pong", ""})
+				break
+			}
+			writeSimple(rc.w, "PONG")
+		default:
+			s.exec(rc.w, args)
+		}
+		err = rc.w.Flush()
+		rc.mutex.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *RedisServer) subscribe(rc *redisConn, channels ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, channel := range channels {
+		if _, found := s.subscribers[channel]; !found {
+			s.subscribers[channel] = map[*redisConn]struct{}{}
+		}
+		s.subscribers[channel][rc] = struct{}{}
+		rc.subscribed[channel] = struct{}{}
+		_, _ = fmt.Fprintf(rc.w, "*3\r\n")
+		writeBulk(rc.w, []byte("subscribe"))
+		writeBulk(rc.w, []byte(channel))
+		writeInt(rc.w, int64(len(rc.subscribed)))
+	}
+}
+
+func (s *RedisServer) unsubscribe(rc *redisConn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for channel := range rc.subscribed {
+		delete(s.subscribers[channel], rc)
+	}
+}
+
+func (s *RedisServer) publish(channel, message string) int64 {
+	s.mutex.Lock()
+	var receivers []*redisConn
+	for rc := range s.subscribers[channel] {
+		receivers = append(receivers, rc)
+	}
+	s.mutex.Unlock()
+	for _, rc := range receivers {
+		rc.mutex.Lock()
+		writeArray(rc.w, []string{"message", channel, message})
+		_ = rc.w.Flush()
+		rc.mutex.Unlock()
+	}
+	return int64(len(receivers))
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *RedisServer) exec(w *bufio.Writer, args []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	for k, v := range s.items {
+		if v.expired(now) {
+			delete(s.items, k)
+		}
+	}
+	cmd := strings.ToLower(args[0])
+	args = args[1:]
+	switch cmd {
+	case "get":
+		if len(args) != 1 {
+			writeError(w, "wrong number of arguments for 'get' command")
+			return
+		}
+		if it, found := s.items[args[0]]; found {
+			writeBulk(w, it.value)
+			return
+		}
+		writeNil(w)
+	case "mget":
+		_, _ = fmt.Fprintf(w, "*%d\r\n", len(args))
+		for _, k := range args {
+			if it, found := s.items[k]; found {
+				writeBulk(w, it.value)
+			} else {
+				writeNil(w)
+			}
+		}
+	case "set":
+		if len(args) < 2 {
+			writeError(w, "wrong number of arguments for 'set' command")
+			return
+		}
+		it := redisItem{value: []byte(args[1])}
+		for i := 2; i < len(args); i++ {
+			switch strings.ToLower(args[i]) {
+			case "ex", "px":
+				if i+1 >= len(args) {
+					writeError(w, "syntax error")
+					return
+				}
+				n, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil {
+					writeError(w, "value is not an integer or out of range")
+					return
+				}
+				unit := time.Second
+				if strings.EqualFold(args[i], "px") {
+					unit = time.Millisecond
+				}
+				it.expires = now.Add(time.Duration(n) * unit)
+				i++
+			}
+		}
+		s.items[args[0]] = it
+		writeSimple(w, "OK")
+	case "del", "unlink":
+		var n int64
+		for _, k := range args {
+			if _, found := s.items[k]; found {
+				delete(s.items, k)
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "exists":
+		var n int64
+		for _, k := range args {
+			if _, found := s.items[k]; found {
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "expire", "pexpire":
+		if len(args) != 2 {
+			writeError(w, "wrong number of arguments")
+			return
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			writeError(w, "value is not an integer or out of range")
+			return
+		}
+		it, found := s.items[args[0]]
+		if !found {
+			writeInt(w, 0)
+			return
+		}
+		unit := time.Second
+		if cmd == "pexpire" {
+			unit = time.Millisecond
+		}
+		it.expires = now.Add(time.Duration(n) * unit)
+		s.items[args[0]] = it
+		writeInt(w, 1)
+	case "persist":
+		it, found := s.items[args[0]]
+		if !found || it.expires.IsZero() {
+			writeInt(w, 0)
+			return
+		}
+		it.expires = time.Time{}
+		s.items[args[0]] = it
+		writeInt(w, 1)
+	case "ttl", "pttl":
+		it, found := s.items[args[0]]
+		switch {
+		case !found:
+			writeInt(w, -2)
+		case it.expires.IsZero():
+			writeInt(w, -1)
+		case cmd == "ttl":
+			writeInt(w, int64(it.expires.Sub(now).Round(time.Second)/time.Second))
+		default:
+			writeInt(w, int64(it.expires.Sub(now)/time.Millisecond))
+		}
+	case "keys":
+		var keys []string
+		for k := range s.items {
+			if globMatch(args[0], k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		writeArray(w, keys)
+	case "scan":
+		s.scan(w, args)
+	case "flushall", "flushdb":
+		s.items = map[string]redisItem{}
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("unknown command '%s'", cmd))
+	}
+}
+
+func (s *RedisServer) scan(w *bufio.Writer, args []string) {
+	if len(args) < 1 {
+		writeError(w, "wrong number of arguments for 'scan' command")
+		return
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil {
+		writeError(w, "invalid cursor")
+		return
+	}
+	match, count := "*", 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToLower(args[i]) {
+		case "match":
+			match = args[i+1]
+		case "count":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+	var keys []string
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	start := 0
+	if cursor != 0 {
+		after, found := s.cursors[cursor]
+		if !found {
+			writeError(w, "invalid cursor")
+			return
+		}
+		delete(s.cursors, cursor)
+		start = sort.Search(len(keys), func(i int) bool {
+			return keys[i] > after
+		})
+	}
+	end := start + count
+	if end >= len(keys) {
+		end = len(keys)
+	}
+	var page []string
+	for _, k := range keys[start:end] {
+		if globMatch(match, k) {
+			page = append(page, k)
+		}
+	}
+	next := 0
+	if end < len(keys) {
+		s.nextCursor++
+		next = s.nextCursor
+		s.cursors[next] = keys[end-1]
+	}
+	_, _ = fmt.Fprintf(w, "*2\r\n")
+	writeBulk(w, []byte(strconv.Itoa(next)))
+	writeArray(w, page)
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeError(w *bufio.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "-ERR %s\r\n", s)
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	_, _ = fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeNil(w *bufio.Writer) {
+	_, _ = fmt.Fprintf(w, "$-1\r\n")
+}
+
+func writeBulk(w *bufio.Writer, b []byte) {
+	_, _ = fmt.Fprintf(w, "$%d\r\n", len(b))
+	_, _ = w.Write(b)
+	_, _ = w.WriteString("\r\n")
+}
+
+func writeArray(w *bufio.Writer, values []string) {
+	_, _ = fmt.Fprintf(w, "*%d\r\n", len(values))
+	for _, v := range values {
+		writeBulk(w, []byte(v))
+	}
+}
+
+// globMatch matches keys like redis, unlike path.Match a * also matches /.
+func globMatch(pattern, key string) bool {
+	expr := &strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			expr.WriteString(pattern[i : i+end+1])
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	ok, _ := regexp.MatchString(expr.String(), key)
+	return ok
+}
@@ -0,0 +1,160 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	_ Cache = &ChaosCache{}
+
+	ErrChaos = errors.New("chaos cache injected error")
+)
+
+// LatencyDistribution adds a uniformly distributed delay between Min and Max to a call.
+type LatencyDistribution struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// ChaosFault is a single scripted outcome for a call, used by ChaosConfig.Schedule.
+type ChaosFault struct {
+	Latency time.Duration
+	Err     error
+	Drop    bool
+}
+
+type ChaosConfig struct {
+	// Seed makes the random faults deterministic, 0 seeds from the current time.
+	Seed      int64
+	Latency   map[CacheCmd]LatencyDistribution
+	ErrorRate map[CacheCmd]float64
+	// DropRate is the chance a SET silently succeeds without writing.
+	DropRate float64
+	// Err is returned for injected errors, defaults to ErrChaos.
+	Err error
+	// Schedule is consumed one fault per call before falling back to the random faults.
+	Schedule []ChaosFault
+}
+
+// ChaosCache injects latency, errors and dropped writes into the wrapped cache for resilience tests.
+type ChaosCache struct {
+	Cache
+	mutex    *sync.Mutex
+	rand     *rand.Rand
+	config   ChaosConfig
+	schedule []ChaosFault
+	enabled  bool
+}
+
+func NewChaosCache(c Cache, config ChaosConfig) *ChaosCache {
+	cc := &ChaosCache{
+		mutex:   &sync.Mutex{},
+		enabled: true,
+	}
+	cc.SetConfig(config)
+	cc.Cache = WrapCache(c, cc.intercept)
+	return cc
+}
+
+// NewChaosTieredCache wraps every tier in its own ChaosCache so tiers can fail independently.
+func NewChaosTieredCache(getter GetCache, config ChaosConfig, cacheList ...Cache) (Cache, []*ChaosCache) {
+	var tiers []Cache
+	var chaos []*ChaosCache
+	for i, c := range cacheList {
+		tierConfig := config
+		if config.Seed != 0 {
+			tierConfig.Seed = config.Seed + int64(i)
+		}
+		cc := NewChaosCache(c, tierConfig)
+		tiers = append(tiers, cc)
+		chaos = append(chaos, cc)
+	}
+	return NewTieredCache(getter, tiers...), chaos
+}
+
+// SetConfig replaces the active faults and reseeds the random source.
+func (c *ChaosCache) SetConfig(config ChaosConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if config.Err == nil {
+		config.Err = ErrChaos
+	}
+	c.config = config
+	c.schedule = append([]ChaosFault{}, config.Schedule...)
+	c.rand = rand.New(rand.NewSource(seed)) //nolint:gosec
+}
+
+// SetSchedule queues faults for the next calls.
+func (c *ChaosCache) SetSchedule(faults ...ChaosFault) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.schedule = append([]ChaosFault{}, faults...)
+}
+
+func (c *ChaosCache) Enable() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.enabled = true
+}
+
+// Disable passes every call straight through until Enable is called.
+func (c *ChaosCache) Disable() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.enabled = false
+}
+
+func (c *ChaosCache) nextFault(cmd CacheCmd) ChaosFault {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.enabled {
+		return ChaosFault{}
+	}
+	if len(c.schedule) > 0 {
+		f := c.schedule[0]
+		c.schedule = c.schedule[1:]
+		return f
+	}
+	var f ChaosFault
+	if l, found := c.config.Latency[cmd]; found {
+		f.Latency = l.Min
+		if l.Max > l.Min {
+			f.Latency += time.Duration(c.rand.Int63n(int64(l.Max - l.Min)))
+		}
+	}
+	if rate := c.config.ErrorRate[cmd]; rate > 0 && c.rand.Float64() < rate {
+		f.Err = c.config.Err
+	}
+	if cmd == CacheCmdSET && c.config.DropRate > 0 && c.rand.Float64() < c.config.DropRate {
+		f.Drop = true
+	}
+	return f
+}
+
+func (c *ChaosCache) intercept(ctx context.Context, op *Operation, next Invoker) error {
+	f := c.nextFault(op.Cmd)
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.Drop && op.Cmd == CacheCmdSET {
+		return nil
+	}
+	return next(ctx, op)
+}
@@ -0,0 +1,51 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaosCache(t *testing.T) {
+	ctx := context.Background()
+	newCache := func() Cache {
+		return NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	}
+	outcomes := func(seed int64) []bool {
+		c := NewChaosCache(newCache(), ChaosConfig{Seed: seed, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 0.5}})
+		var o []bool
+		for i := 0; i < 20; i++ {
+			_, err := c.GetCache(ctx, "", "key")
+			o = append(o, errors.Is(err, ErrChaos))
+		}
+		return o
+	}
+	assert.Equal(t, outcomes(42), outcomes(42))
+
+	c := NewChaosCache(newCache(), ChaosConfig{Seed: 1, DropRate: 1})
+	assert.NoError(t, c.SetCache(ctx, "", "key", "value"))
+	_, err := c.GetCache(ctx, "", "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	c.SetConfig(ChaosConfig{Seed: 1})
+	c.SetSchedule(ChaosFault{Err: ErrChaos})
+	assert.ErrorIs(t, c.SetCache(ctx, "", "key", "value"), ErrChaos)
+	assert.NoError(t, c.SetCache(ctx, "", "key", "value"))
+
+	c.SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 1}})
+	c.Disable()
+	data, err := c.GetCache(ctx, "", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(data))
+
+	tiered, tiers := NewChaosTieredCache(nil, ChaosConfig{Seed: 1}, newCache(), newCache())
+	assert.NoError(t, tiered.SetCache(ctx, "", "key", "value"))
+	tiers[0].SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 1}})
+	data, err = tiered.GetCache(ctx, "", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(data))
+}
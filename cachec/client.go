@@ -0,0 +1,304 @@
+package cachec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Seann-Moser/cutil/logc"
+	cache "github.com/patrickmn/go-cache"
+	"go.uber.org/zap"
+)
+
+var (
+	defaultClient    atomic.Pointer[Client]
+	defaultCacheOnce sync.Once
+	defaultCache     Cache
+)
+
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// KeyStrategy builds the cache key for a value type and the group/key parts.
+type KeyStrategy func(typeName string, keys ...string) string
+
+func MD5KeyStrategy(typeName string, keys ...string) string {
+	return GetMD5Hash(fmt.Sprintf("%s_%s", typeName, strings.Join(keys, "_")))
+}
+
+// Client owns the cache, monitor, codec and key strategy used by the cache helpers.
+type Client struct {
+	cache   Cache
+	monitor CacheMonitor
+	codec   Codec
+	keys    KeyStrategy
+	// generations caches the key generation of the tenants.
+	generations *generations
+}
+
+type ClientOption func(c *Client)
+
+func WithCache(cache Cache) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+func WithMonitor(monitor CacheMonitor) ClientOption {
+	return func(c *Client) {
+		c.monitor = monitor
+	}
+}
+
+func WithCodec(codec Codec) ClientOption {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithGenerationTTL sets how long the key generation of a tenant is reused before it is read again, 0 reads it for
+// every key. Flushes made by other clients are seen after at most ttl.
+func WithGenerationTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.generations = newGenerations(ttl)
+	}
+}
+
+func WithKeyStrategy(keys KeyStrategy) ClientOption {
+	return func(c *Client) {
+		c.keys = keys
+	}
+}
+
+// NewClient returns a client with its own monitor, without WithCache it uses the context cache.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		monitor:     NewMonitor(),
+		codec:       JSONCodec{},
+		keys:        MD5KeyStrategy,
+		generations: newGenerations(DefaultGenerationTTL),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Default returns the client used by the package level helpers.
+// It uses the context cache and GlobalCacheMonitor unless replaced with SetDefaultClient.
+func Default() *Client {
+	if c := defaultClient.Load(); c != nil {
+		return c
+	}
+	defaultClient.CompareAndSwap(nil, &Client{
+		codec:       JSONCodec{},
+		keys:        MD5KeyStrategy,
+		generations: newGenerations(DefaultGenerationTTL),
+	})
+	return defaultClient.Load()
+}
+
+func SetDefaultClient(c *Client) {
+	defaultClient.Store(c)
+}
+
+// fallbackCache is used when the context does not carry a cache. DefaultCache is read on every call, while it is
+// nil a GoCache shared by the process is used.
+func fallbackCache() Cache {
+	if c := DefaultCache; c != nil {
+		return c
+	}
+	defaultCacheOnce.Do(func() {
+		defaultCache = NewGoCache(cache.New(5*time.Minute, time.Minute), cache.DefaultExpiration, "backup")
+	})
+	return defaultCache
+}
+
+func (c *Client) Cache(ctx context.Context) Cache {
+	if c.cache != nil {
+		return c.cache
+	}
+	return GetCacheFromContext(ctx)
+}
+
+func (c *Client) Monitor() CacheMonitor {
+	if c.monitor != nil {
+		return c.monitor
+	}
+	return GlobalCacheMonitor
+}
+
+func (c *Client) Key(typeName string, keys ...string) string {
+	return c.keys(typeName, keys...)
+}
+
+// context makes sure the monitor reads and writes its group state in the client cache.
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.cache == nil {
+		return ctx
+	}
+	return ContextWithCache(ctx, c.cache)
+}
+
+func (c *Client) Get(ctx context.Context, group, key string, out interface{}) error {
+	return c.get(ctx, c.Cache(ctx), typeNameOf(out), group, key, out)
+}
+
+func (c *Client) Set(ctx context.Context, group, key string, data interface{}) error {
+	return c.set(ctx, c.Cache(ctx), getType(data), group, key, data, 0, false)
+}
+
+func (c *Client) SetWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, data interface{}) error {
+	return c.set(ctx, c.Cache(ctx), getType(data), group, key, data, cacheTimeout, true)
+}
+
+// Delete removes the entry stored for the type of out.
+func (c *Client) Delete(ctx context.Context, group, key string, out interface{}) error {
+	return c.delete(ctx, typeNameOf(out), group, key)
+}
+
+// GetSet reads into out, on any error the getter result is cached and assigned to out.
+func (c *Client) GetSet(ctx context.Context, cacheTimeout time.Duration, group, key string, out interface{}, gtr func(ctx context.Context) (interface{}, error)) error {
+	if rv := reflect.ValueOf(out); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(out)}
+	}
+	ctx, span := startGetSet(ctx, "GetSet", group)
+	defer span.End()
+	if err := c.Get(ctx, group, key, out); err == nil {
+		span.SetAttributes(AttrHit.Bool(true))
+		return nil
+	}
+	span.SetAttributes(AttrHit.Bool(false))
+	nv, err := load(ctx, group, gtr)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(out).Elem()
+	v := reflect.ValueOf(nv)
+	if !v.IsValid() {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if !v.Type().AssignableTo(rv.Type()) {
+		return fmt.Errorf("getter returned %s, expected %s", v.Type(), rv.Type())
+	}
+	rv.Set(v)
+	_ = c.set(ctx, c.Cache(ctx), typeNameOf(out), group, key, nv, cacheTimeout, true)
+	return nil
+}
+
+type wrapper struct {
+	Data interface{} `json:"data"`
+}
+
+// encode wraps data the same way as Wrapper[T] so entries are readable by every client.
+func (c *Client) encode(data interface{}) ([]byte, error) {
+	return c.codec.Marshal(wrapper{Data: data})
+}
+
+func (c *Client) decode(data []byte, out interface{}) error {
+	return c.codec.Unmarshal(data, &wrapper{Data: out})
+}
+
+func (c *Client) get(ctx context.Context, cache Cache, typeName, group, key string, out interface{}) error {
+	ctx = c.context(ctx)
+	if group != "" && c.Monitor().HasGroupKeyBeenUpdated(ctx, group) {
+		logc.Debug(ctx, "group has been updated", zap.String("group", group), zap.String("key", key))
+		return ErrCacheUpdated
+	}
+	k, err := c.groupKey(ctx, cache, typeName, group, key)
+	if err != nil {
+		return err
+	}
+	data, err := cache.GetCache(withLookup(ctx, c, typeName, key), group, k)
+	if err != nil {
+		return err
+	}
+	err = c.decode(data, out)
+	if err != nil {
+		return err
+	}
+	logc.Debug(ctx, "using cache", zap.String("group", group), zap.String("key", key))
+	return nil
+}
+
+func (c *Client) set(ctx context.Context, cache Cache, typeName, group, key string, data interface{}, cacheTimeout time.Duration, expire bool) error {
+	ctx = c.context(ctx)
+	item, err := c.encode(data)
+	if err != nil {
+		return err
+	}
+	k, err := c.groupKey(ctx, cache, typeName, group, key)
+	if err != nil {
+		return err
+	}
+	if expire {
+		err = cache.SetCacheWithExpiration(ctx, cacheTimeout, group, k, item)
+	} else {
+		err = cache.SetCache(ctx, group, k, item)
+	}
+	if err != nil {
+		logc.Debug(ctx, "failed setting cache", zap.String("group", group), zap.String("key", key))
+		return err
+	}
+	if strings.EqualFold(group, GroupPrefix) {
+		return nil
+	}
+	logc.Debug(ctx, "set cache", zap.String("group", group), zap.String("key", key))
+	return published(ctx, c.Monitor().UpdateCache(ctx, group, key))
+}
+
+func (c *Client) delete(ctx context.Context, typeName, group, key string) error {
+	ctx = c.context(ctx)
+	cache := c.Cache(ctx)
+	k, err := c.groupKey(ctx, cache, typeName, group, key)
+	if err != nil {
+		return err
+	}
+	if err := cache.DeleteKey(ctx, k); err != nil {
+		return err
+	}
+	return published(ctx, c.Monitor().Publish(ctx, Event{Type: EventDelete, Group: TenantGroup(ctx, group), Key: key}))
+}
+
+// published logs the events the broker failed to publish, the write itself succeeded and other processes only miss
+// the event.
+func published(ctx context.Context, err error) error {
+	if errors.Is(err, ErrPublish) {
+		logc.Error(ctx, "failed publishing cache event", zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+func typeName[T any]() string {
+	var d T
+	return getType(d)
+}
+
+// typeNameOf returns the type name of the value out points to, matching the name used for GetKey[T].
+func typeNameOf(out interface{}) string {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return getType(out)
+	}
+	return getType(rv.Elem().Interface())
+}
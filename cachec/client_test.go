@@ -0,0 +1,80 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"first", "second"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, name)))
+			assert.NoError(t, c.Set(ctx, "group", "key", name))
+
+			var v string
+			assert.NoError(t, c.Get(ctx, "group", "key", &v))
+			assert.Equal(t, name, v)
+
+			var loaded ResponseData
+			err := c.GetSet(ctx, time.Minute, "group", "response", &loaded, func(ctx context.Context) (interface{}, error) {
+				return ResponseData{Message: name}, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, name, loaded.Message)
+
+			var cached ResponseData
+			assert.NoError(t, c.Get(ctx, "group", "response", &cached))
+			assert.Equal(t, name, cached.Message)
+
+			assert.NoError(t, c.Delete(ctx, "group", "key", &v))
+			assert.ErrorIs(t, c.Get(ctx, "group", "key", &v), ErrCacheMiss)
+		})
+	}
+}
+
+func TestClientCompatibleWithDefault(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	goCache := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	ctx := ContextWithCache(context.Background(), goCache)
+	c := NewClient(WithCache(goCache))
+	assert.NoError(t, c.Set(ctx, "", "key", ResponseData{Message: "client"}))
+
+	v, err := Get[ResponseData](ctx, "", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "client", v.Message)
+	assert.Equal(t, GetKey[ResponseData]("", "key"), c.Key("ResponseData", "", "key"))
+}
+
+func TestClientGetSetInvalidOut(t *testing.T) {
+	c := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	gtr := func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	}
+	var v string
+	var nilOut *string
+	for _, out := range []interface{}{nil, v, nilOut} {
+		assert.Error(t, c.GetSet(context.Background(), time.Minute, "group", "key", out, gtr))
+	}
+}
+
+func TestDefaultCacheReplaced(t *testing.T) {
+	defer func() {
+		DefaultCache = nil
+	}()
+	backup := GetCacheFromContext(context.Background())
+	assert.Same(t, backup, GetCacheFromContext(context.Background()))
+
+	first := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "first")
+	DefaultCache = first
+	assert.Same(t, first, GetCacheFromContext(context.Background()), "DefaultCache is honoured after first use")
+	second := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "second")
+	DefaultCache = second
+	assert.Same(t, second, GetCacheFromContext(context.Background()))
+}
@@ -0,0 +1,61 @@
+package cachec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	redis "github.com/Seann-Moser/ociredis"
+	"github.com/orijtech/gomemcache/memcache"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/cachec/cachectest"
+)
+
+func newRedisCache(t *testing.T) *cachec.RedisCache {
+	s := cachectest.StartRedis(t)
+	return cachec.NewRedisCache(redis.NewClient(&redis.Options{Addr: s.Addr(), Context: context.Background()}), time.Minute, "conformance", true)
+}
+
+func newMemcache(t *testing.T) *cachec.MemCache {
+	s := cachectest.StartMemcache(t)
+	return cachec.NewMemcache(memcache.New(s.Addr()), time.Minute, "conformance", true)
+}
+
+func newGoCache(t *testing.T) *cachec.GoCache {
+	return cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "conformance")
+}
+
+func TestConformance(t *testing.T) {
+	factories := map[string]cachectest.Factory{
+		"go cache": func(t *testing.T) cachec.Cache {
+			return newGoCache(t)
+		},
+		"redis": func(t *testing.T) cachec.Cache {
+			return newRedisCache(t)
+		},
+		"memcache": func(t *testing.T) cachec.Cache {
+			return newMemcache(t)
+		},
+		"tiered": func(t *testing.T) cachec.Cache {
+			return cachec.NewTieredCache(nil, newGoCache(t), newRedisCache(t))
+		},
+		"hot keys": func(t *testing.T) cachec.Cache {
+			return cachec.NewHotKeyCache(newRedisCache(t), cachec.WithHotKeyThreshold(1))
+		},
+		"sharded": func(t *testing.T) cachec.Cache {
+			return cachec.NewShardedCache(map[string]cachec.Cache{
+				"a": newGoCache(t),
+				"b": newRedisCache(t),
+				"c": newGoCache(t),
+			}, cachec.WithReplicas(1))
+		},
+	}
+	for name, factory := range factories {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			cachectest.RunConformance(t, factory)
+		})
+	}
+}
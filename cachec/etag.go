@@ -0,0 +1,74 @@
+package cachec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMiddleware answers conditional GET/HEAD requests from the update timestamps of the groups the endpoint depends on.
+// Matching If-None-Match or If-Modified-Since requests get a 304 without running the handler.
+// Validators are only sent once every group has a recorded update, a nil client uses Default().
+func ETagMiddleware(client *Client, groups ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			c := client
+			if c == nil {
+				c = Default()
+			}
+			ctx := c.context(r.Context())
+			etag, lastModified, ok := groupValidators(ctx, c, r, groups)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+			if notModified(r, etag, lastModified) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// groupValidators returns a weak ETag for the request URL and group versions and the latest group update.
+func groupValidators(ctx context.Context, c *Client, r *http.Request, groups []string) (string, time.Time, bool) {
+	var lastModified time.Time
+	parts := []string{r.URL.Path, r.URL.RawQuery}
+	for _, g := range groups {
+		updated, err := c.Monitor().LastUpdated(ctx, g)
+		if err != nil || updated.IsZero() {
+			return "", time.Time{}, false
+		}
+		if updated.After(lastModified) {
+			lastModified = updated
+		}
+		parts = append(parts, fmt.Sprintf("%s=%d", g, updated.UnixNano()))
+	}
+	return fmt.Sprintf(`W/"%s"`, GetMD5Hash(strings.Join(parts, "_"))), lastModified, true
+}
+
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ims)
+}
@@ -0,0 +1,55 @@
+package cachec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	ctx := client.context(context.Background())
+	calls := 0
+	handler := ETagMiddleware(client, "users", "roles")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("ok"))
+	}))
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	assert.NoError(t, client.Monitor().UpdateCache(ctx, "users", "1"))
+	assert.NoError(t, client.Monitor().UpdateCache(ctx, "roles", "1"))
+	w = serve("", "")
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, lastModified)
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, http.StatusNotModified, serve("If-None-Match", `"other", `+etag).Code)
+	assert.Equal(t, http.StatusNotModified, serve("If-Modified-Since", lastModified).Code)
+	assert.Equal(t, http.StatusOK, serve("If-None-Match", `"other"`).Code)
+	assert.Equal(t, http.StatusOK, serve("If-Modified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)).Code)
+	assert.Equal(t, 4, calls)
+
+	assert.NoError(t, client.Monitor().UpdateCache(ctx, "roles", "2"))
+	w = serve("If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"), "updates within the same second change the etag")
+}
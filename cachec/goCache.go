@@ -2,9 +2,10 @@ package cachec
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -19,6 +20,19 @@ type GoCache struct {
 	defaultDuration time.Duration
 	cacher          *cache.Cache
 	cacheTags       CacheTags
+	interceptors    interceptorChain
+	quotaOnce       *sync.Once
+	quotas          atomic.Pointer[groupQuotas]
+	writes          *keyLocks
+}
+
+// keyLocks serializes the writes of a key, so expire cannot write back a value replaced or deleted while it ran.
+type keyLocks [64]sync.Mutex
+
+func (l *keyLocks) lock(key string) func() {
+	m := &l[crc32.ChecksumIEEE([]byte(key))%uint32(len(l))]
+	m.Lock()
+	return m.Unlock
 }
 
 func (c *GoCache) GetName() string {
@@ -41,16 +55,23 @@ func NewGoCacheFromFlags(prefix string) *GoCache {
 }
 
 func NewGoCache(cacher *cache.Cache, defaultDuration time.Duration, instance string) *GoCache {
+	tags := NewCacheTags("go-cache", instance)
 	return &GoCache{
 		cacher:          cacher,
 		defaultDuration: defaultDuration,
-		cacheTags:       NewCacheTags("go-cache", instance),
+		cacheTags:       tags,
+		interceptors:    interceptorChain{MetricsInterceptor(tags), TracingInterceptor(tags.CacheName)},
+		quotaOnce:       &sync.Once{},
+		writes:          &keyLocks{},
 	}
 }
 
 func (c *GoCache) DeleteKey(ctx context.Context, key string) error {
-	c.cacher.Delete(key)
-	return nil
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdDELETE, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		defer c.writes.lock(op.Key)()
+		c.cacher.Delete(op.Key)
+		return nil
+	})
 }
 
 func (c *GoCache) Ping(ctx context.Context) error {
@@ -61,23 +82,15 @@ func (c *GoCache) Close() {
 
 }
 func (c *GoCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
-	var err error
-	s := c.cacheTags.record(ctx, CacheCmdSET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
-		}
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdSET, Cache: c.GetName(), Group: group, Key: key, item: item}, func(ctx context.Context, op *Operation) error {
+		defer c.writes.lock(op.Key)()
+		ttl, err := c.admit(ctx, op.Group, op.Key, op.ValueSize, cacheTimeout)
 		if err != nil {
-			return CacheStatusERR
+			return err
 		}
-		return CacheStatusOK
+		c.cacher.Set(op.Key, item, ttl)
+		return nil
 	})
-
-	defer func() {
-		s(err)
-	}()
-
-	c.cacher.Set(key, item, cacheTimeout)
-	return nil
 }
 
 func (c *GoCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
@@ -85,33 +98,65 @@ func (c *GoCache) SetCache(ctx context.Context, group, key string, item interfac
 }
 
 func (c *GoCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
-	var cacheErr error
-	s := c.cacheTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
+	var output []byte
+	err := c.interceptors.run(ctx, &Operation{Cmd: CacheCmdGET, Cache: c.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		data, found := c.cacher.Get(op.Key)
+		if !found {
+			return ErrCacheMiss
 		}
+		b, err := encodeItem(data)
 		if err != nil {
-			return CacheStatusERR
+			return err
 		}
-		return CacheStatusFOUND
+		output = b
+		op.Size = len(output)
+		return nil
 	})
-	defer func() {
-		s(cacheErr)
-	}()
-	if data, found := c.cacher.Get(key); !found {
-		cacheErr = ErrCacheMiss
-		return nil, ErrCacheMiss
-	} else {
-		switch v := data.(type) {
-		case string:
-			return []byte(v), nil
-		default:
-			b, err := json.Marshal(data)
-			if err != nil {
-				cacheErr = err
-				return nil, err
-			}
-			return b, nil
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *GoCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTTL, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		_, expires, found := c.cacher.GetWithExpiration(op.Key)
+		if !found {
+			return ErrCacheMiss
 		}
+		if expires.IsZero() {
+			ttl = NoExpiration
+			return nil
+		}
+		ttl = time.Until(expires)
+		return nil
+	})
+	return ttl, err
+}
+
+func (c *GoCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultDuration
 	}
+	return c.expire(ctx, key, ttl)
+}
+
+func (c *GoCache) Persist(ctx context.Context, key string) error {
+	return c.expire(ctx, key, cache.NoExpiration)
+}
+
+func (c *GoCache) expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		defer c.writes.lock(op.Key)()
+		data, found := c.cacher.Get(op.Key)
+		if !found {
+			return ErrCacheMiss
+		}
+		if q := c.quotas.Load(); q != nil {
+			ttl = q.ttl(op.Key, ttl)
+		}
+		c.cacher.Set(op.Key, data, ttl)
+		return nil
+	})
 }
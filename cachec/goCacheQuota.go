@@ -0,0 +1,324 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	MetricQuotaRejected = "cachec.quota.rejected"
+	MetricQuotaEvicted  = "cachec.quota.evicted"
+)
+
+var ErrQuotaExceeded = errors.New("cache group quota exceeded")
+
+type QuotaPolicy int
+
+const (
+	// QuotaReject fails inserts that do not fit with ErrQuotaExceeded.
+	QuotaReject QuotaPolicy = iota
+	// QuotaEvictOldest evicts the oldest entries of the group until the insert fits.
+	QuotaEvictOldest
+)
+
+// GroupQuota limits a group of a GoCache, zero values are unlimited.
+type GroupQuota struct {
+	MaxEntries int
+	MaxBytes   int64
+	// MaxTTL caps the expiration of the entries, including entries without one.
+	MaxTTL time.Duration
+	Policy QuotaPolicy
+}
+
+type GroupQuotaState struct {
+	Group    string
+	Quota    GroupQuota
+	Entries  int
+	Bytes    int64
+	Rejected uint64
+	Evicted  uint64
+}
+
+// SetGroupQuota limits group, it applies to entries written from now on.
+// The GoCache takes over the OnEvicted callback of its go-cache to keep the usage current.
+func (c *GoCache) SetGroupQuota(group string, quota GroupQuota) {
+	c.groupQuotas().set(group, quota)
+}
+
+// SetDefaultGroupQuota limits every group without its own quota, each group is counted separately.
+func (c *GoCache) SetDefaultGroupQuota(quota GroupQuota) {
+	q := c.groupQuotas()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.fallback = &quota
+}
+
+// GroupQuotaStates returns the usage of the groups with a quota, sorted by group.
+func (c *GoCache) GroupQuotaStates() []GroupQuotaState {
+	q := c.quotas.Load()
+	if q == nil {
+		return nil
+	}
+	return q.states()
+}
+
+func (c *GoCache) groupQuotas() *groupQuotas {
+	c.quotaOnce.Do(func() {
+		q := newGroupQuotas(c.GetName(), func(key string) bool {
+			_, found := c.cacher.Get(key)
+			return found
+		})
+		c.cacher.OnEvicted(func(key string, _ interface{}) {
+			q.release(key)
+		})
+		c.quotas.Store(q)
+	})
+	return c.quotas.Load()
+}
+
+// admit reserves room for the item in its group, evicting older entries when the policy allows it.
+// It returns the expiration capped by the quota, size is only called when the cache has quotas.
+func (c *GoCache) admit(ctx context.Context, group, key string, size func() int, ttl time.Duration) (time.Duration, error) {
+	q := c.quotas.Load()
+	if q == nil {
+		return ttl, nil
+	}
+	evict, ttl, err := q.admit(ctx, group, key, int64(size()), ttl)
+	for _, k := range evict {
+		c.cacher.Delete(k)
+	}
+	if err != nil {
+		// the rejected write replaces the entry, readers must not keep seeing the old value.
+		c.cacher.Delete(key)
+	}
+	return ttl, err
+}
+
+type groupQuotas struct {
+	mutex    *sync.Mutex
+	cache    string
+	present  func(key string) bool
+	quotas   map[string]GroupQuota
+	fallback *GroupQuota
+	usage    map[string]*groupUsage
+	// keys maps the tracked keys to their group.
+	keys     map[string]string
+	seq      uint64
+	rejected metric.Int64Counter
+	evicted  metric.Int64Counter
+}
+
+type groupUsage struct {
+	quota    GroupQuota
+	entries  map[string]quotaEntry
+	order    []quotaOrder
+	bytes    int64
+	rejected uint64
+	evicted  uint64
+}
+
+type quotaEntry struct {
+	size int64
+	seq  uint64
+}
+
+type quotaOrder struct {
+	key string
+	seq uint64
+}
+
+func newGroupQuotas(cache string, present func(key string) bool) *groupQuotas {
+	q := &groupQuotas{
+		mutex:   &sync.Mutex{},
+		cache:   cache,
+		present: present,
+		quotas:  map[string]GroupQuota{},
+		usage:   map[string]*groupUsage{},
+		keys:    map[string]string{},
+	}
+	meter := MeterProvider().Meter(meterName)
+	q.rejected, _ = meter.Int64Counter(MetricQuotaRejected, metric.WithDescription("Inserts rejected by a group quota"))
+	q.evicted, _ = meter.Int64Counter(MetricQuotaEvicted, metric.WithDescription("Entries evicted by a group quota"))
+	return q
+}
+
+func (q *groupQuotas) set(group string, quota GroupQuota) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.quotas[group] = quota
+	if u, found := q.usage[group]; found {
+		u.quota = quota
+	}
+}
+
+func (q *groupQuotas) quota(group string) (GroupQuota, bool) {
+	if quota, found := q.quotas[group]; found {
+		return quota, true
+	}
+	// the monitor's group records are only limited by an explicit quota.
+	if q.fallback != nil && group != GroupPrefix {
+		return *q.fallback, true
+	}
+	return GroupQuota{}, false
+}
+
+func (q *groupQuotas) admit(ctx context.Context, group, key string, size int64, ttl time.Duration) ([]string, time.Duration, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	quota, limited := q.quota(group)
+	if previous, found := q.keys[key]; found && (previous != group || !limited) {
+		q.remove(key)
+	}
+	if !limited {
+		return nil, ttl, nil
+	}
+	if quota.MaxTTL > 0 && (ttl <= 0 || ttl > quota.MaxTTL) {
+		ttl = quota.MaxTTL
+	}
+	u, found := q.usage[group]
+	if !found {
+		u = &groupUsage{entries: map[string]quotaEntry{}}
+		q.usage[group] = u
+	}
+	u.quota = quota
+	old, replacing := u.entries[key]
+	entries, bytes := len(u.entries)+1, u.bytes+size
+	if replacing {
+		entries, bytes = entries-1, bytes-old.size
+	}
+	var evict []string
+	if !u.fits(entries, bytes) && quota.Policy == QuotaEvictOldest && (quota.MaxBytes <= 0 || size <= quota.MaxBytes) {
+		for !u.fits(entries, bytes) && len(u.order) > 0 {
+			oldest := u.order[0]
+			u.order = u.order[1:]
+			e, live := u.entries[oldest.key]
+			if !live || e.seq != oldest.seq || oldest.key == key {
+				continue
+			}
+			evict = append(evict, oldest.key)
+			entries, bytes = entries-1, bytes-e.size
+			q.remove(oldest.key)
+		}
+		u.evicted += uint64(len(evict))
+		q.count(ctx, q.evicted, group, len(evict))
+	}
+	if !u.fits(entries, bytes) {
+		if replacing {
+			q.remove(key)
+		}
+		u.rejected++
+		q.count(ctx, q.rejected, group, 1)
+		return evict, ttl, ErrQuotaExceeded
+	}
+	if replacing {
+		q.remove(key)
+	}
+	q.seq++
+	u.entries[key] = quotaEntry{size: size, seq: q.seq}
+	u.order = append(u.order, quotaOrder{key: key, seq: q.seq})
+	u.bytes += size
+	q.keys[key] = group
+	if len(u.order) > 2*len(u.entries)+64 {
+		u.compact()
+	}
+	return evict, ttl, nil
+}
+
+func (q *groupQuotas) count(ctx context.Context, counter metric.Int64Counter, group string, n int) {
+	if counter == nil || n == 0 {
+		return
+	}
+	counter.Add(ctx, int64(n), metric.WithAttributes(AttrCache.String(q.cache), AttrGroup.String(group)))
+}
+
+// release drops a key deleted or expired by go-cache, unless it has been written again since.
+func (q *groupQuotas) release(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.present(key) {
+		return
+	}
+	q.remove(key)
+}
+
+// group returns the group a key was admitted to, "" for keys that are not tracked.
+func (q *groupQuotas) group(key string) string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.keys[key]
+}
+
+// remove must be called with the mutex held.
+func (q *groupQuotas) remove(key string) {
+	group, found := q.keys[key]
+	if !found {
+		return
+	}
+	delete(q.keys, key)
+	if u, found := q.usage[group]; found {
+		if e, found := u.entries[key]; found {
+			u.bytes -= e.size
+			delete(u.entries, key)
+		}
+	}
+}
+
+// ttl caps the expiration of an existing key by the quota of its group.
+func (q *groupQuotas) ttl(key string, ttl time.Duration) time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	group, found := q.keys[key]
+	if !found {
+		return ttl
+	}
+	if quota, limited := q.quota(group); limited && quota.MaxTTL > 0 && (ttl <= 0 || ttl > quota.MaxTTL) {
+		return quota.MaxTTL
+	}
+	return ttl
+}
+
+func (q *groupQuotas) states() []GroupQuotaState {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var output []GroupQuotaState
+	for group, u := range q.usage {
+		output = append(output, GroupQuotaState{
+			Group:    group,
+			Quota:    u.quota,
+			Entries:  len(u.entries),
+			Bytes:    u.bytes,
+			Rejected: u.rejected,
+			Evicted:  u.evicted,
+		})
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].Group < output[j].Group
+	})
+	return output
+}
+
+func (u *groupUsage) fits(entries int, bytes int64) bool {
+	if u.quota.MaxEntries > 0 && entries > u.quota.MaxEntries {
+		return false
+	}
+	if u.quota.MaxBytes > 0 && bytes > u.quota.MaxBytes {
+		return false
+	}
+	return true
+}
+
+// compact drops the order records of replaced and removed entries.
+func (u *groupUsage) compact() {
+	order := make([]quotaOrder, 0, len(u.entries))
+	for _, o := range u.order {
+		if e, found := u.entries[o.key]; found && e.seq == o.seq {
+			order = append(order, o)
+		}
+	}
+	u.order = order
+}
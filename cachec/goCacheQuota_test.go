@@ -0,0 +1,81 @@
+package cachec
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoCacheGroupQuotaReject(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	c.SetGroupQuota("adhoc", GroupQuota{MaxEntries: 2})
+
+	assert.NoError(t, c.SetCache(ctx, "adhoc", "a", "1"))
+	assert.NoError(t, c.SetCache(ctx, "adhoc", "b", "1"))
+	assert.ErrorIs(t, c.SetCache(ctx, "adhoc", "c", "1"), ErrQuotaExceeded)
+	assert.NoError(t, c.SetCache(ctx, "adhoc", "a", "2"))
+	assert.NoError(t, c.SetCache(ctx, "other", "c", "1"))
+
+	assert.NoError(t, c.DeleteKey(ctx, "b"))
+	assert.NoError(t, c.SetCache(ctx, "adhoc", "c", "1"))
+
+	states := c.GroupQuotaStates()
+	assert.Len(t, states, 1)
+	assert.Equal(t, "adhoc", states[0].Group)
+	assert.Equal(t, 2, states[0].Entries)
+	assert.Equal(t, uint64(1), states[0].Rejected)
+
+	c.SetGroupQuota("sized", GroupQuota{MaxBytes: 10})
+	assert.NoError(t, c.SetCache(ctx, "sized", "d", "1"))
+	assert.ErrorIs(t, c.SetCache(ctx, "sized", "d", "this value does not fit"), ErrQuotaExceeded)
+	_, err := c.GetCache(ctx, "sized", "d")
+	assert.ErrorIs(t, err, ErrCacheMiss, "a rejected overwrite drops the old value")
+	states = c.GroupQuotaStates()
+	assert.Equal(t, "sized", states[1].Group)
+	assert.Equal(t, 0, states[1].Entries)
+	assert.Equal(t, int64(0), states[1].Bytes)
+}
+
+func TestGoCacheGroupQuotaEvict(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	c.SetDefaultGroupQuota(GroupQuota{MaxBytes: 30, Policy: QuotaEvictOldest})
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.SetCache(ctx, "adhoc", fmt.Sprint(i), "0123456789"))
+	}
+	_, err := c.GetCache(ctx, "adhoc", "0")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = c.GetCache(ctx, "adhoc", "4")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.SetCache(ctx, "adhoc", "large", "this value is larger than the whole quota"), ErrQuotaExceeded)
+
+	states := c.GroupQuotaStates()
+	assert.Len(t, states, 1)
+	assert.Equal(t, 2, states[0].Entries)
+	assert.Equal(t, int64(24), states[0].Bytes)
+	assert.Equal(t, uint64(3), states[0].Evicted)
+	assert.Equal(t, uint64(1), states[0].Rejected)
+}
+
+func TestGoCacheGroupQuotaTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	c.SetGroupQuota("short", GroupQuota{MaxTTL: time.Second})
+
+	assert.NoError(t, c.SetCacheWithExpiration(ctx, time.Hour, "short", "key", "value"))
+	ttl, err := c.TTL(ctx, "key")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second)
+
+	assert.NoError(t, c.Persist(ctx, "key"))
+	ttl, err = c.TTL(ctx, "key")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, ttl, time.Second)
+	assert.Greater(t, ttl, time.Duration(0))
+}
@@ -0,0 +1,179 @@
+package cachec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Seann-Moser/cutil/logc"
+	"github.com/patrickmn/go-cache"
+	"go.uber.org/zap"
+)
+
+const SnapshotVersion uint32 = 1
+
+var (
+	ErrSnapshotFormat   = errors.New("invalid cache snapshot")
+	ErrSnapshotVersion  = errors.New("unsupported cache snapshot version")
+	ErrSnapshotChecksum = errors.New("cache snapshot checksum mismatch")
+
+	snapshotMagic = [6]byte{'C', 'C', 'S', 'N', 'A', 'P'}
+)
+
+// snapshot files are the magic, a big endian version, the gob encoded payload and its sha256.
+type snapshot struct {
+	Created time.Time
+	Entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	Key string
+	// Group is the quota group of the entry, "" when the cache had no quotas.
+	Group string
+	Value []byte
+	// Expires is in unix nanoseconds, 0 never expires.
+	Expires int64
+}
+
+// Snapshot writes every unexpired entry with its expiration to w.
+func (c *GoCache) Snapshot(w io.Writer) error {
+	snap := snapshot{Created: time.Now()}
+	q := c.quotas.Load()
+	for key, item := range c.cacher.Items() {
+		value, err := encodeItem(item.Object)
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", key, err)
+		}
+		e := snapshotEntry{Key: key, Value: value, Expires: item.Expiration}
+		if q != nil {
+			e.Group = q.group(key)
+		}
+		snap.Entries = append(snap.Entries, e)
+	}
+	payload := &bytes.Buffer{}
+	if err := gob.NewEncoder(payload).Encode(snap); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(payload.Bytes())
+	if _, err := w.Write(snapshotMagic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, SnapshotVersion); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// Restore loads a snapshot written by Snapshot, expired entries are skipped.
+// Entries are admitted to the group quotas like writes, the ones that do not fit are skipped.
+// It returns the number of entries restored.
+func (c *GoCache) Restore(r io.Reader) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	header := len(snapshotMagic) + 4
+	if len(data) < header+sha256.Size || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return 0, ErrSnapshotFormat
+	}
+	if v := binary.BigEndian.Uint32(data[len(snapshotMagic):header]); v != SnapshotVersion {
+		return 0, fmt.Errorf("%w: %d", ErrSnapshotVersion, v)
+	}
+	payload := data[header : len(data)-sha256.Size]
+	if sum := sha256.Sum256(payload); !bytes.Equal(sum[:], data[len(data)-sha256.Size:]) {
+		return 0, ErrSnapshotChecksum
+	}
+	var snap snapshot
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotFormat, err)
+	}
+	now := time.Now().UnixNano()
+	restored := 0
+	for _, e := range snap.Entries {
+		ttl := cache.NoExpiration
+		if e.Expires > 0 {
+			if e.Expires <= now {
+				continue
+			}
+			ttl = time.Duration(e.Expires - now)
+		}
+		if c.restore(e, ttl) {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+func (c *GoCache) restore(e snapshotEntry, ttl time.Duration) bool {
+	defer c.writes.lock(e.Key)()
+	ttl, err := c.admit(context.Background(), e.Group, e.Key, func() int { return len(e.Value) }, ttl)
+	if err != nil {
+		return false
+	}
+	c.cacher.Set(e.Key, e.Value, ttl)
+	return true
+}
+
+// SnapshotFile writes the snapshot to a temporary file next to path and renames it into place.
+func (c *GoCache) SnapshotFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := c.Snapshot(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// RestoreFile loads the snapshot at path, a missing file restores nothing.
+func (c *GoCache) RestoreFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return c.Restore(f)
+}
+
+// StartSnapshots writes a snapshot to path every interval and once more when ctx is done.
+func (c *GoCache) StartSnapshots(ctx context.Context, path string, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if err := c.SnapshotFile(path); err != nil {
+					logc.Error(ctx, "failed writing final cache snapshot", zap.String("path", path), zap.Error(err))
+				}
+				return
+			case <-ticker.C:
+				if err := c.SnapshotFile(path); err != nil {
+					logc.Error(ctx, "failed writing cache snapshot", zap.String("path", path), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package cachec
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoCacheSnapshot(t *testing.T) {
+	ctx := context.Background()
+	src := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	assert.NoError(t, src.SetCache(ctx, "", "string", "value"))
+	assert.NoError(t, src.SetCache(ctx, "", "bytes", []byte("raw")))
+	assert.NoError(t, src.SetCacheWithExpiration(ctx, 50*time.Millisecond, "", "expiring", "value"))
+	assert.NoError(t, src.Persist(ctx, "string"))
+
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	assert.NoError(t, src.SnapshotFile(path))
+	time.Sleep(100 * time.Millisecond)
+
+	dst := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	n, err := dst.RestoreFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := dst.GetCache(ctx, "", "string")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(v))
+	ttl, err := dst.TTL(ctx, "string")
+	assert.NoError(t, err)
+	assert.Equal(t, NoExpiration, ttl)
+
+	v, err = dst.GetCache(ctx, "", "bytes")
+	assert.NoError(t, err)
+	assert.Equal(t, "raw", string(v))
+	ttl, err = dst.TTL(ctx, "bytes")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+
+	_, err = dst.GetCache(ctx, "", "expiring")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	n, err = dst.RestoreFile(filepath.Join(t.TempDir(), "missing.snap"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestGoCacheRestoreQuota(t *testing.T) {
+	ctx := context.Background()
+	src := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	src.SetGroupQuota("adhoc", GroupQuota{MaxEntries: 10})
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, src.SetCache(ctx, "adhoc", key, "value"))
+	}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, src.Snapshot(buf))
+
+	dst := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	dst.SetGroupQuota("adhoc", GroupQuota{MaxEntries: 2})
+	n, err := dst.Restore(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	states := dst.GroupQuotaStates()
+	assert.Len(t, states, 1)
+	assert.Equal(t, "adhoc", states[0].Group)
+	assert.Equal(t, 2, states[0].Entries)
+	assert.Equal(t, int64(14), states[0].Bytes)
+}
+
+func TestGoCacheRestoreCorrupt(t *testing.T) {
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	assert.NoError(t, c.SetCache(context.Background(), "", "key", "value"))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, c.Snapshot(buf))
+	data := buf.Bytes()
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	_, err := c.Restore(bytes.NewReader(corrupt))
+	assert.ErrorIs(t, err, ErrSnapshotChecksum)
+
+	version := append([]byte{}, data...)
+	version[len(snapshotMagic)+3] = 9
+	_, err = c.Restore(bytes.NewReader(version))
+	assert.ErrorIs(t, err, ErrSnapshotVersion)
+
+	_, err = c.Restore(bytes.NewReader([]byte("not a snapshot")))
+	assert.ErrorIs(t, err, ErrSnapshotFormat)
+}
@@ -0,0 +1,195 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+var (
+	ErrNoLoader = errors.New("group has no loader")
+
+	groupRegistry = &registry{
+		mutex:  &sync.RWMutex{},
+		groups: map[string]GroupHandle{},
+	}
+)
+
+// GroupHandle is the type independent view of a Group used by the registry.
+type GroupHandle interface {
+	Info() GroupInfo
+	Invalidate(ctx context.Context) error
+}
+
+type GroupInfo struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	TTL       time.Duration `json:"ttl"`
+	HasLoader bool          `json:"has_loader"`
+}
+
+// Group binds a value type, group name, TTL and loader so call sites don't repeat them.
+type Group[T any] struct {
+	name   string
+	ttl    time.Duration
+	loader func(ctx context.Context, key string) (T, error)
+	client *Client
+}
+
+type GroupOption[T any] func(g *Group[T])
+
+func WithGroupTTL[T any](ttl time.Duration) GroupOption[T] {
+	return func(g *Group[T]) {
+		g.ttl = ttl
+	}
+}
+
+func WithGroupLoader[T any](loader func(ctx context.Context, key string) (T, error)) GroupOption[T] {
+	return func(g *Group[T]) {
+		g.loader = loader
+	}
+}
+
+// WithGroupClient uses the client instead of the default client.
+func WithGroupClient[T any](client *Client) GroupOption[T] {
+	return func(g *Group[T]) {
+		g.client = client
+	}
+}
+
+// NewGroup creates a group handle and registers it, a group with the same name and type is replaced.
+func NewGroup[T any](name string, opts ...GroupOption[T]) *Group[T] {
+	g := &Group[T]{
+		name: name,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	groupRegistry.register(g)
+	return g
+}
+
+// Groups lists the registered group handles sorted by name.
+func Groups() []GroupHandle {
+	return groupRegistry.list()
+}
+
+func (g *Group[T]) getClient() *Client {
+	if g.client != nil {
+		return g.client
+	}
+	return Default()
+}
+
+func (g *Group[T]) Name() string {
+	return g.name
+}
+
+func (g *Group[T]) Info() GroupInfo {
+	return GroupInfo{
+		Name:      g.name,
+		Type:      typeName[T](),
+		TTL:       g.ttl,
+		HasLoader: g.loader != nil,
+	}
+}
+
+func (g *Group[T]) Get(ctx context.Context, key string) (*T, error) {
+	c := g.getClient()
+	var output T
+	if err := c.get(ctx, c.Cache(ctx), typeName[T](), g.name, key, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (g *Group[T]) Set(ctx context.Context, key string, data T) error {
+	c := g.getClient()
+	if g.ttl <= 0 {
+		return c.set(ctx, c.Cache(ctx), typeName[T](), g.name, key, data, 0, false)
+	}
+	return c.set(ctx, c.Cache(ctx), typeName[T](), g.name, key, data, g.ttl, true)
+}
+
+// GetOrLoad returns the cached value or loads, caches and returns it.
+func (g *Group[T]) GetOrLoad(ctx context.Context, key string) (T, error) {
+	ctx, span := startGetSet(ctx, "GetOrLoad", g.name)
+	defer span.End()
+	if v, err := g.Get(ctx, key); err == nil {
+		span.SetAttributes(AttrHit.Bool(true))
+		return *v, nil
+	}
+	span.SetAttributes(AttrHit.Bool(false))
+	var output T
+	if g.loader == nil {
+		return output, ErrNoLoader
+	}
+	output, err := load(ctx, g.name, func(ctx context.Context) (T, error) {
+		return g.loader(ctx, key)
+	})
+	if err != nil {
+		return output, err
+	}
+	_ = g.Set(ctx, key, output)
+	return output, nil
+}
+
+func (g *Group[T]) Delete(ctx context.Context, key string) error {
+	return g.getClient().delete(ctx, typeName[T](), g.name, key)
+}
+
+// Invalidate deletes every key recorded for the group and marks the group as updated.
+func (g *Group[T]) Invalidate(ctx context.Context) error {
+	c := g.getClient()
+	ctx = c.context(ctx)
+	cache := c.Cache(ctx)
+	var err error
+	if keys, e := c.Monitor().GetGroupKeys(ctx, g.name); e == nil {
+		for k := range keys {
+			key, e := c.groupKey(ctx, cache, typeName[T](), g.name, k)
+			if e == nil {
+				e = cache.DeleteKey(ctx, key)
+			}
+			err = multierr.Combine(err, e)
+		}
+	}
+	if gc, ok := groupCache(cache); ok {
+		_, e := gc.DeleteGroup(ctx, TenantGroup(ctx, g.name))
+		err = multierr.Combine(err, e)
+	}
+	err = multierr.Combine(err, c.Monitor().MarkUpdated(ctx, g.name))
+	return multierr.Combine(err, c.Monitor().Publish(ctx, Event{Type: EventFlush, Group: TenantGroup(ctx, g.name)}))
+}
+
+type registry struct {
+	mutex  *sync.RWMutex
+	groups map[string]GroupHandle
+}
+
+func (r *registry) register(g GroupHandle) {
+	info := g.Info()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.groups[info.Name+"_"+info.Type] = g
+}
+
+func (r *registry) list() []GroupHandle {
+	r.mutex.RLock()
+	var output []GroupHandle
+	for _, g := range r.groups {
+		output = append(output, g)
+	}
+	r.mutex.RUnlock()
+	sort.Slice(output, func(i, j int) bool {
+		a, b := output[i].Info(), output[j].Info()
+		if a.Name == b.Name {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return output
+}
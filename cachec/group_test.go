@@ -0,0 +1,57 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	loads := 0
+	roles := NewGroup[[]string]("roles",
+		WithGroupTTL[[]string](time.Minute),
+		WithGroupClient[[]string](client),
+		WithGroupLoader[[]string](func(ctx context.Context, key string) ([]string, error) {
+			loads++
+			return []string{"admin", key}, nil
+		}),
+	)
+
+	v, err := roles.GetOrLoad(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "user"}, v)
+	v, err = roles.GetOrLoad(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "user"}, v)
+	assert.Equal(t, 1, loads)
+
+	assert.NoError(t, roles.Delete(ctx, "user"))
+	_, err = roles.Get(ctx, "user")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, roles.Set(ctx, "other", []string{"reader"}))
+	assert.NoError(t, roles.Invalidate(ctx))
+	_, err = roles.Get(ctx, "other")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	keys, err := client.Monitor().GetGroupKeys(client.context(ctx), "roles")
+	assert.NoError(t, err)
+	assert.NotContains(t, keys, "", "invalidations do not record a key")
+
+	_, err = NewGroup[string]("no-loader", WithGroupClient[string](client)).GetOrLoad(ctx, "key")
+	assert.ErrorIs(t, err, ErrNoLoader)
+
+	var found bool
+	for _, g := range Groups() {
+		if g.Info().Name == "roles" {
+			found = true
+			assert.Equal(t, time.Minute, g.Info().TTL)
+			assert.True(t, g.Info().HasLoader)
+		}
+	}
+	assert.True(t, found)
+}
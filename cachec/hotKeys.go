@@ -0,0 +1,405 @@
+package cachec
+
+import (
+	"context"
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.uber.org/multierr"
+)
+
+const (
+	DefaultHotKeyCapacity  = 64
+	DefaultHotKeyThreshold = 100
+	DefaultHotKeyWindow    = 10 * time.Second
+	DefaultHotKeyTTL       = 5 * time.Second
+)
+
+var _ Cache = &HotKeyCache{}
+var _ GroupCache = &HotKeyCache{}
+
+// HotKeyCache counts reads of the wrapped cache, typically a RedisCache or TieredCache, with a top-K sketch and
+// copies keys read at least threshold times per window into a short-TTL local tier.
+// Writes and deletes through the HotKeyCache drop the local copy, other processes see them once the copy expires.
+// Reads that overlap a write of their key are not promoted, so the local tier never holds a value older than the write.
+type HotKeyCache struct {
+	cache     Cache
+	local     Cache
+	mutex     *sync.Mutex
+	sketch    *topK
+	threshold uint64
+	window    time.Duration
+	ttl       time.Duration
+	decayed   time.Time
+	promoted  map[string]time.Time
+	versions  *writeVersions
+}
+
+// writeVersions counts the writes of the keys, striped so the counters do not grow with the keys.
+// Keys sharing a stripe only skip some promotions.
+type writeVersions [64]uint64
+
+func (v *writeVersions) of(key string) *uint64 {
+	return &v[crc32.ChecksumIEEE([]byte(key))%uint32(len(v))]
+}
+
+// HotKey is a tracked key with its estimated reads in the current window.
+type HotKey struct {
+	Key      string    `json:"key"`
+	Count    uint64    `json:"count"`
+	Promoted bool      `json:"promoted"`
+	Expires  time.Time `json:"expires,omitempty"`
+}
+
+type HotKeyOption func(h *HotKeyCache)
+
+// WithHotKeyCapacity sets the number of keys tracked by the sketch.
+func WithHotKeyCapacity(k int) HotKeyOption {
+	return func(h *HotKeyCache) {
+		if k > 0 {
+			h.sketch = newTopK(k)
+		}
+	}
+}
+
+// WithHotKeyThreshold sets the reads per window a key needs to be promoted.
+func WithHotKeyThreshold(reads uint64) HotKeyOption {
+	return func(h *HotKeyCache) {
+		if reads > 0 {
+			h.threshold = reads
+		}
+	}
+}
+
+// WithHotKeyWindow sets how often the counts are halved, so keys that cool down drop out.
+func WithHotKeyWindow(window time.Duration) HotKeyOption {
+	return func(h *HotKeyCache) {
+		if window > 0 {
+			h.window = window
+		}
+	}
+}
+
+// WithHotKeyTTL sets how long promoted keys are served from the local tier.
+func WithHotKeyTTL(ttl time.Duration) HotKeyOption {
+	return func(h *HotKeyCache) {
+		if ttl > 0 {
+			h.ttl = ttl
+		}
+	}
+}
+
+// WithHotKeyLocal replaces the local tier, a GoCache is used by default.
+func WithHotKeyLocal(local Cache) HotKeyOption {
+	return func(h *HotKeyCache) {
+		h.local = local
+	}
+}
+
+func NewHotKeyCache(c Cache, opts ...HotKeyOption) *HotKeyCache {
+	h := &HotKeyCache{
+		cache:     c,
+		mutex:     &sync.Mutex{},
+		sketch:    newTopK(DefaultHotKeyCapacity),
+		threshold: DefaultHotKeyThreshold,
+		window:    DefaultHotKeyWindow,
+		ttl:       DefaultHotKeyTTL,
+		decayed:   time.Now(),
+		promoted:  map[string]time.Time{},
+		versions:  &writeVersions{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.local == nil {
+		h.local = NewGoCache(cache.New(h.ttl, time.Minute), h.ttl, "hotkeys")
+	}
+	return h
+}
+
+// HotKeys returns the tracked keys, hottest first.
+func (h *HotKeyCache) HotKeys() []HotKey {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := time.Now()
+	h.decay(now)
+	var output []HotKey
+	for _, e := range h.sketch.top() {
+		hk := HotKey{Key: e.key, Count: e.guaranteed()}
+		if expires, found := h.promoted[e.key]; found && now.Before(expires) {
+			hk.Promoted = true
+			hk.Expires = expires
+		}
+		output = append(output, hk)
+	}
+	return output
+}
+
+// HotKeysHandler writes HotKeys as json, for debug endpoints.
+func (h *HotKeyCache) HotKeysHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.HotKeys())
+	})
+}
+
+func (h *HotKeyCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	if h.isPromoted(key) {
+		if v, err := h.local.GetCache(ctx, group, key); err == nil {
+			return v, nil
+		}
+	}
+	version := h.version(key)
+	v, err := h.cache.GetCache(ctx, group, key)
+	if err != nil {
+		return nil, err
+	}
+	// group stamps stay uncached so invalidations are not delayed by the local tier.
+	if group != GroupPrefix && h.record(key) {
+		h.promote(ctx, group, key, v, version)
+	}
+	return v, nil
+}
+
+// version returns the write version of the key, read before the value so promote can tell whether it is stale.
+func (h *HotKeyCache) version(key string) uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return *h.versions.of(key)
+}
+
+// promote copies the value into the local tier, never for longer than the entry has left.
+// The copy is dropped when the key was written since version was read, the value may predate the write.
+func (h *HotKeyCache) promote(ctx context.Context, group, key string, v []byte, version uint64) {
+	ttl := h.ttl
+	if remaining, err := h.cache.TTL(ctx, key); err == nil && remaining > 0 && remaining < ttl {
+		ttl = remaining
+	}
+	if h.local.SetCacheWithExpiration(ctx, ttl, group, key, v) != nil {
+		return
+	}
+	h.mutex.Lock()
+	if *h.versions.of(key) != version {
+		h.mutex.Unlock()
+		_ = h.local.DeleteKey(ctx, key)
+		return
+	}
+	h.promoted[key] = time.Now().Add(ttl)
+	h.mutex.Unlock()
+}
+
+// record counts a read and reports whether the key should be promoted.
+func (h *HotKeyCache) record(key string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := time.Now()
+	h.decay(now)
+	e := h.sketch.add(key)
+	if e.guaranteed() < h.threshold {
+		return false
+	}
+	expires, found := h.promoted[key]
+	return !found || !now.Before(expires)
+}
+
+func (h *HotKeyCache) decay(now time.Time) {
+	for now.Sub(h.decayed) >= h.window {
+		h.sketch.halve()
+		h.decayed = h.decayed.Add(h.window)
+		if len(h.sketch.entries) == 0 {
+			h.decayed = now
+		}
+	}
+	for key, expires := range h.promoted {
+		if !now.Before(expires) {
+			delete(h.promoted, key)
+		}
+	}
+}
+
+func (h *HotKeyCache) isPromoted(key string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	expires, found := h.promoted[key]
+	return found && time.Now().Before(expires)
+}
+
+// demote drops the local copies and bumps the write version of the keys. Writes demote before and after changing
+// the wrapped cache, reads that started before the change finished then skip their promotion.
+func (h *HotKeyCache) demote(ctx context.Context, keys ...string) error {
+	var err error
+	h.mutex.Lock()
+	for _, key := range keys {
+		delete(h.promoted, key)
+		*h.versions.of(key)++
+	}
+	h.mutex.Unlock()
+	for _, key := range keys {
+		err = multierr.Combine(err, h.local.DeleteKey(ctx, key))
+	}
+	return err
+}
+
+// write runs fn between two demotions of the key.
+func (h *HotKeyCache) write(ctx context.Context, key string, fn func() error) error {
+	_ = h.demote(ctx, key)
+	defer func() { _ = h.demote(ctx, key) }()
+	return fn()
+}
+
+func (h *HotKeyCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return h.write(ctx, key, func() error {
+		return h.cache.SetCache(ctx, group, key, item)
+	})
+}
+
+func (h *HotKeyCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	return h.write(ctx, key, func() error {
+		return h.cache.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item)
+	})
+}
+
+func (h *HotKeyCache) DeleteKey(ctx context.Context, key string) error {
+	return h.write(ctx, key, func() error {
+		return h.cache.DeleteKey(ctx, key)
+	})
+}
+
+func (h *HotKeyCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return h.cache.TTL(ctx, key)
+}
+
+func (h *HotKeyCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return h.write(ctx, key, func() error {
+		return h.cache.Touch(ctx, key, ttl)
+	})
+}
+
+func (h *HotKeyCache) Persist(ctx context.Context, key string) error {
+	return h.write(ctx, key, func() error {
+		return h.cache.Persist(ctx, key)
+	})
+}
+
+func (h *HotKeyCache) Ping(ctx context.Context) error {
+	return h.cache.Ping(ctx)
+}
+
+func (h *HotKeyCache) Close() {
+	h.cache.Close()
+	h.local.Close()
+}
+
+func (h *HotKeyCache) GetName() string {
+	return "HOTKEYS_" + h.cache.GetName()
+}
+
+func (h *HotKeyCache) GetParentCaches() map[string]Cache {
+	return h.cache.GetParentCaches()
+}
+
+func (h *HotKeyCache) GroupKeyLayout() bool {
+	_, ok := groupCache(h.cache)
+	return ok
+}
+
+func (h *HotKeyCache) GroupKeys(ctx context.Context, group string) ([]string, error) {
+	gc, ok := groupCache(h.cache)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return gc.GroupKeys(ctx, group)
+}
+
+// DeleteGroup deletes the group from the wrapped cache and drops the promoted keys of the group.
+func (h *HotKeyCache) DeleteGroup(ctx context.Context, group string) (int, error) {
+	gc, ok := groupCache(h.cache)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	var keys []string
+	h.mutex.Lock()
+	for key := range h.promoted {
+		if strings.HasPrefix(key, GroupKeyPrefix(group)) {
+			keys = append(keys, key)
+		}
+	}
+	h.mutex.Unlock()
+	_ = h.demote(ctx, keys...)
+	n, err := gc.DeleteGroup(ctx, group)
+	return n, multierr.Combine(err, h.demote(ctx, keys...))
+}
+
+// topK is a space saving sketch, it keeps the k most frequent keys with an upper bound on the error of each count.
+type topK struct {
+	k       int
+	entries map[string]*topKEntry
+}
+
+type topKEntry struct {
+	key   string
+	count uint64
+	// err is the count inherited from the evicted entry, the true count is at least count - err.
+	err uint64
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, entries: make(map[string]*topKEntry, k)}
+}
+
+func (e *topKEntry) guaranteed() uint64 {
+	return e.count - e.err
+}
+
+func (t *topK) add(key string) *topKEntry {
+	if e, found := t.entries[key]; found {
+		e.count++
+		return e
+	}
+	if len(t.entries) < t.k {
+		e := &topKEntry{key: key, count: 1}
+		t.entries[key] = e
+		return e
+	}
+	var min *topKEntry
+	for _, e := range t.entries {
+		if min == nil || e.count < min.count {
+			min = e
+		}
+	}
+	delete(t.entries, min.key)
+	e := &topKEntry{key: key, count: min.count + 1, err: min.count}
+	t.entries[key] = e
+	return e
+}
+
+// halve ages the counts so the sketch follows the current traffic.
+func (t *topK) halve() {
+	for key, e := range t.entries {
+		e.count /= 2
+		e.err /= 2
+		if e.count == 0 {
+			delete(t.entries, key)
+		}
+	}
+}
+
+func (t *topK) top() []topKEntry {
+	output := make([]topKEntry, 0, len(t.entries))
+	for _, e := range t.entries {
+		output = append(output, *e)
+	}
+	sort.Slice(output, func(i, j int) bool {
+		if output[i].count == output[j].count {
+			return output[i].key < output[j].key
+		}
+		return output[i].count > output[j].count
+	})
+	return output
+}
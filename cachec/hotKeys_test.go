@@ -0,0 +1,110 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotKeyCache(t *testing.T) {
+	ctx := context.Background()
+	reads := 0
+	counter := func(ctx context.Context, op *Operation, next Invoker) error {
+		if op.Cmd == CacheCmdGET {
+			reads++
+		}
+		return next(ctx, op)
+	}
+	backing := WrapCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), counter)
+	h := NewHotKeyCache(backing, WithHotKeyThreshold(3), WithHotKeyTTL(time.Minute))
+
+	assert.NoError(t, h.SetCache(ctx, "config", "features", "v1"))
+	for i := 0; i < 5; i++ {
+		v, err := h.GetCache(ctx, "config", "features")
+		assert.NoError(t, err)
+		assert.Equal(t, `"v1"`, string(v))
+	}
+	assert.Equal(t, 3, reads)
+
+	hot := h.HotKeys()
+	assert.Len(t, hot, 1)
+	assert.Equal(t, "features", hot[0].Key)
+	assert.Equal(t, uint64(3), hot[0].Count)
+	assert.True(t, hot[0].Promoted)
+
+	assert.NoError(t, h.SetCache(ctx, "config", "features", "v2"))
+	v, err := h.GetCache(ctx, "config", "features")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v2"`, string(v))
+	assert.Equal(t, 4, reads)
+
+	reads = 0
+	assert.NoError(t, h.SetCache(ctx, GroupPrefix, "stamp", 1))
+	for i := 0; i < 5; i++ {
+		_, err := h.GetCache(ctx, GroupPrefix, "stamp")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 5, reads)
+}
+
+func TestHotKeyCacheEntryTTL(t *testing.T) {
+	ctx := context.Background()
+	h := NewHotKeyCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), WithHotKeyThreshold(1), WithHotKeyTTL(time.Minute))
+	assert.NoError(t, h.SetCacheWithExpiration(ctx, 50*time.Millisecond, "group", "short", "value"))
+	_, err := h.GetCache(ctx, "group", "short")
+	assert.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	_, err = h.GetCache(ctx, "group", "short")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestHotKeyCacheConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+	var h *HotKeyCache
+	write := false
+	// the write lands after the read loaded the value and before it is promoted.
+	racer := func(ctx context.Context, op *Operation, next Invoker) error {
+		err := next(ctx, op)
+		if op.Cmd == CacheCmdGET && write {
+			write = false
+			assert.NoError(t, h.SetCache(ctx, "config", "features", "v2"))
+		}
+		return err
+	}
+	backing := WrapCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), racer)
+	h = NewHotKeyCache(backing, WithHotKeyThreshold(1), WithHotKeyTTL(time.Minute))
+
+	assert.NoError(t, h.SetCache(ctx, "config", "features", "v1"))
+	write = true
+	v, err := h.GetCache(ctx, "config", "features")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(v))
+	assert.False(t, h.isPromoted("features"), "a value read before a write is not promoted")
+
+	v, err = h.GetCache(ctx, "config", "features")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v2"`, string(v))
+	assert.True(t, h.isPromoted("features"))
+}
+
+func TestTopK(t *testing.T) {
+	sketch := newTopK(2)
+	for _, key := range []string{"a", "a", "a", "b", "c"} {
+		sketch.add(key)
+	}
+	top := sketch.top()
+	assert.Len(t, top, 2)
+	assert.Equal(t, "a", top[0].key)
+	assert.Equal(t, uint64(3), top[0].guaranteed())
+	assert.Equal(t, "c", top[1].key)
+	assert.Equal(t, uint64(2), top[1].count)
+	assert.Equal(t, uint64(1), top[1].guaranteed())
+
+	sketch.halve()
+	top = sketch.top()
+	assert.Len(t, top, 2)
+	assert.Equal(t, uint64(1), top[0].count)
+}
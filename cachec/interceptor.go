@@ -0,0 +1,182 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var _ Cache = &InterceptedCache{}
+
+// Operation describes a single cache call as seen by an Interceptor.
+// Key and Group may be rewritten before calling next, Size, Latency and Err are filled in once the call returns.
+// Writes of unencoded items leave Size at 0 until ValueSize is called, so only the callers that need it pay for
+// the encoding.
+type Operation struct {
+	Cmd     CacheCmd
+	Cache   string
+	Group   string
+	Key     string
+	Size    int
+	Latency time.Duration
+	Err     error
+
+	item  interface{}
+	sized bool
+}
+
+// ValueSize returns the size of the value read or written, encoding the written item the first time it is needed.
+func (op *Operation) ValueSize() int {
+	if !op.sized && op.item != nil {
+		op.Size = valueSize(op.item)
+		op.sized = true
+	}
+	return op.Size
+}
+
+type Invoker func(ctx context.Context, op *Operation) error
+
+// Interceptor runs around a cache operation, it must call next to continue the chain.
+type Interceptor func(ctx context.Context, op *Operation, next Invoker) error
+
+type interceptorChain []Interceptor
+
+func (ic interceptorChain) run(ctx context.Context, op *Operation, call Invoker) error {
+	next := func(ctx context.Context, op *Operation) error {
+		start := time.Now()
+		op.Err = call(ctx, op)
+		op.Latency = time.Since(start)
+		return op.Err
+	}
+	for i := len(ic) - 1; i >= 0; i-- {
+		interceptor, invoker := ic[i], next
+		next = func(ctx context.Context, op *Operation) error {
+			return interceptor(ctx, op, invoker)
+		}
+	}
+	return next(ctx, op)
+}
+
+// RunInterceptors runs call through the interceptors in order, for backends implemented outside the package.
+func RunInterceptors(ctx context.Context, interceptors []Interceptor, op *Operation, call Invoker) error {
+	return interceptorChain(interceptors).run(ctx, op, call)
+}
+
+func cmdStatus(cmd CacheCmd) Status {
+	return func(err error) CacheStatus {
+		if errors.Is(err, ErrCacheMiss) {
+			return CacheStatusMISSING
+		}
+		if err != nil {
+			return CacheStatusERR
+		}
+		if cmd == CacheCmdGET {
+			return CacheStatusFOUND
+		}
+		return CacheStatusOK
+	}
+}
+
+// MetricsInterceptor records the latency and status of every operation with the given tags.
+func MetricsInterceptor(tags CacheTags) Interceptor {
+	return func(ctx context.Context, op *Operation, next Invoker) error {
+		s := tags.record(ctx, op.Cmd, cmdStatus(op.Cmd))
+		err := next(ctx, op)
+		s(err)
+		return err
+	}
+}
+
+type InterceptedCache struct {
+	cache        Cache
+	interceptors interceptorChain
+}
+
+// WrapCache runs the interceptors, in order, around every Get, Set and Delete of c.
+func WrapCache(c Cache, interceptors ...Interceptor) Cache {
+	return &InterceptedCache{
+		cache:        c,
+		interceptors: interceptors,
+	}
+}
+
+func (i *InterceptedCache) GetName() string {
+	return i.cache.GetName()
+}
+
+func (i *InterceptedCache) GetParentCaches() map[string]Cache {
+	return i.cache.GetParentCaches()
+}
+
+func (i *InterceptedCache) Ping(ctx context.Context) error {
+	return i.cache.Ping(ctx)
+}
+
+func (i *InterceptedCache) Close() {
+	i.cache.Close()
+}
+
+func (i *InterceptedCache) DeleteKey(ctx context.Context, key string) error {
+	return i.interceptors.run(ctx, &Operation{Cmd: CacheCmdDELETE, Cache: i.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		return i.cache.DeleteKey(ctx, op.Key)
+	})
+}
+
+func (i *InterceptedCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return i.interceptors.run(ctx, &Operation{Cmd: CacheCmdSET, Cache: i.GetName(), Group: group, Key: key, item: item}, func(ctx context.Context, op *Operation) error {
+		return i.cache.SetCache(ctx, op.Group, op.Key, item)
+	})
+}
+
+func (i *InterceptedCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	return i.interceptors.run(ctx, &Operation{Cmd: CacheCmdSET, Cache: i.GetName(), Group: group, Key: key, item: item}, func(ctx context.Context, op *Operation) error {
+		return i.cache.SetCacheWithExpiration(ctx, cacheTimeout, op.Group, op.Key, item)
+	})
+}
+
+func (i *InterceptedCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	var data []byte
+	err := i.interceptors.run(ctx, &Operation{Cmd: CacheCmdGET, Cache: i.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		var err error
+		data, err = i.cache.GetCache(ctx, op.Group, op.Key)
+		op.Size = len(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (i *InterceptedCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := i.interceptors.run(ctx, &Operation{Cmd: CacheCmdTTL, Cache: i.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		var err error
+		ttl, err = i.cache.TTL(ctx, op.Key)
+		return err
+	})
+	return ttl, err
+}
+
+func (i *InterceptedCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return i.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: i.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		return i.cache.Touch(ctx, op.Key, ttl)
+	})
+}
+
+func (i *InterceptedCache) Persist(ctx context.Context, key string) error {
+	return i.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: i.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		return i.cache.Persist(ctx, op.Key)
+	})
+}
+
+func valueSize(item interface{}) int {
+	if item == nil {
+		return 0
+	}
+	b, err := encodeItem(item)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
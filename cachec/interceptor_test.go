@@ -0,0 +1,52 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapCache(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	var ops []Operation
+	recorder := func(ctx context.Context, op *Operation, next Invoker) error {
+		calls = append(calls, "recorder")
+		err := next(ctx, op)
+		ops = append(ops, *op)
+		return err
+	}
+	prefixer := func(ctx context.Context, op *Operation, next Invoker) error {
+		calls = append(calls, "prefixer")
+		op.Key = "prefix_" + op.Key
+		return next(ctx, op)
+	}
+	base := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	c := WrapCache(base, recorder, prefixer)
+
+	assert.NoError(t, c.SetCache(ctx, "group", "key", "value"))
+	data, err := base.GetCache(ctx, "group", "prefix_key")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(data))
+
+	data, err = c.GetCache(ctx, "group", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(data))
+
+	assert.NoError(t, c.DeleteKey(ctx, "key"))
+	_, err = c.GetCache(ctx, "group", "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.Equal(t, []string{"recorder", "prefixer", "recorder", "prefixer", "recorder", "prefixer", "recorder", "prefixer"}, calls)
+	assert.Len(t, ops, 4)
+	assert.Equal(t, CacheCmdSET, ops[0].Cmd)
+	assert.Equal(t, 0, ops[0].Size, "writes are only encoded for the size on demand")
+	assert.Equal(t, 7, ops[0].ValueSize())
+	assert.Equal(t, "prefix_key", ops[1].Key)
+	assert.Equal(t, 7, ops[1].Size)
+	assert.Equal(t, CacheCmdDELETE, ops[2].Cmd)
+	assert.ErrorIs(t, ops[3].Err, ErrCacheMiss)
+}
@@ -0,0 +1,95 @@
+package cachec
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type lookupCtxName string
+
+const lookupCtx = lookupCtxName("lookup")
+
+// lookup is the read behind a cache key, it lets getters see the key given to the cache helpers.
+type lookup struct {
+	client   *Client
+	typeName string
+	key      string
+}
+
+func withLookup(ctx context.Context, c *Client, typeName, key string) context.Context {
+	return context.WithValue(ctx, lookupCtx, lookup{client: c, typeName: typeName, key: key})
+}
+
+var _ GetCache = &LoaderRegistry{}
+
+// LoaderRegistry is a TieredCache getter that resolves misses through the typed loader registered for the group.
+// Loaders receive the key passed to the cache helpers rather than the cache key, so reads that bypass the helpers
+// and groups without a loader miss. Concurrent misses of a key share one load.
+type LoaderRegistry struct {
+	mutex   *sync.RWMutex
+	loaders map[string]groupLoader
+	flight  *flightGroup
+}
+
+type groupLoader struct {
+	typeName string
+	ttl      time.Duration
+	load     func(ctx context.Context, key string) (interface{}, error)
+}
+
+func NewLoaderRegistry() *LoaderRegistry {
+	return &LoaderRegistry{
+		mutex:   &sync.RWMutex{},
+		loaders: map[string]groupLoader{},
+		flight:  newFlightGroup(),
+	}
+}
+
+// RegisterLoader sets the loader of group, replacing the previous one. The TieredCache stores loaded values in
+// every tier for ttl, 0 uses the default duration of each tier. Only reads of type T use the loader since the
+// type is part of the cache key.
+func RegisterLoader[T any](r *LoaderRegistry, group string, ttl time.Duration, loader func(ctx context.Context, key string) (T, error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.loaders[group] = groupLoader{
+		typeName: typeName[T](),
+		ttl:      ttl,
+		load: func(ctx context.Context, key string) (interface{}, error) {
+			return loader(ctx, key)
+		},
+	}
+}
+
+// GroupTTL returns how long the loaded values of group are cached, 0 when the tier defaults apply.
+func (r *LoaderRegistry) GroupTTL(group string) time.Duration {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.loaders[group].ttl
+}
+
+func (r *LoaderRegistry) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	l, ok := ctx.Value(lookupCtx).(lookup)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	r.mutex.RLock()
+	loader, found := r.loaders[group]
+	r.mutex.RUnlock()
+	if !found || loader.typeName != l.typeName {
+		return nil, ErrCacheMiss
+	}
+	v, err := r.flight.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		v, err := load(ctx, group, func(ctx context.Context) (interface{}, error) {
+			return loader.load(ctx, l.key)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return l.client.encode(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
@@ -0,0 +1,82 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type loadedRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestLoaderRegistry(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	failed := errors.New("failed")
+	registry := NewLoaderRegistry()
+	RegisterLoader[loadedRole](registry, "roles", time.Hour, func(ctx context.Context, key string) (loadedRole, error) {
+		calls.Add(1)
+		if key == "broken" {
+			return loadedRole{}, failed
+		}
+		return loadedRole{ID: key, Name: "role " + key}, nil
+	})
+	l1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "l2")
+	tiered := NewTieredCache(registry, l1, l2)
+
+	role, err := Peek[loadedRole](ctx, tiered, "roles", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "role admin", role.Name)
+	assert.Equal(t, int32(1), calls.Load())
+	key, err := Default().groupKey(ctx, tiered, typeName[loadedRole](), "roles", "admin")
+	assert.NoError(t, err)
+	for _, c := range []Cache{l1, l2} {
+		ttl, err := c.TTL(ctx, key)
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Hour, ttl, float64(time.Second), "loaded values use the group ttl in %s", c.GetName())
+	}
+
+	empty := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	_, err = Peek[loadedRole](ctx, NewTieredCache(registry, empty, l2), "roles", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load(), "a lower tier hit does not load")
+	ttl, err := empty.TTL(ctx, key)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second), "backfills keep the ttl of the tier that answered")
+
+	_, err = Peek[string](ctx, tiered, "roles", "other")
+	assert.ErrorIs(t, err, ErrCacheMiss, "reads of another type do not use the loader")
+	_, err = Peek[loadedRole](ctx, tiered, "users", "admin")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = tiered.GetCache(ctx, "roles", "raw")
+	assert.ErrorIs(t, err, ErrCacheMiss, "reads that bypass the helpers miss")
+	_, err = Peek[loadedRole](ctx, tiered, "roles", "broken")
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLoaderRegistry_Client(t *testing.T) {
+	ctx := context.Background()
+	registry := NewLoaderRegistry()
+	RegisterLoader[loadedRole](registry, "roles", 0, func(ctx context.Context, key string) (loadedRole, error) {
+		return loadedRole{ID: key}, nil
+	})
+	client := NewClient(WithCache(NewTieredCache(registry, NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))))
+
+	var role loadedRole
+	err := client.Get(ctx, "roles", "admin", &role)
+	// the monitor reports groups it has not seen yet as updated.
+	for i := 0; i < 3 && errors.Is(err, ErrCacheUpdated); i++ {
+		err = client.Get(ctx, "roles", "admin", &role)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", role.ID)
+}
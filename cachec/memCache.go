@@ -2,22 +2,28 @@ package cachec
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/orijtech/gomemcache/memcache"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"go.uber.org/multierr"
 )
 
 var _ Cache = &MemCache{}
 
+// DefaultMemcacheChunkSize keeps chunks below the default 1MB item limit with room for the item overhead.
+const DefaultMemcacheChunkSize = 1000 * 1000
+
 type MemCache struct {
 	memcacheClient  *memcache.Client
 	defaultDuration time.Duration
+	chunkSize       atomic.Int64
 	cacheTags       CacheTags
+	interceptors    interceptorChain
 	enabled         bool
 }
 
@@ -30,19 +36,32 @@ func MemcacheFlags(prefix string) *pflag.FlagSet {
 	fs.StringSlice(prefix+"memcache-addrs", []string{}, "")
 	fs.Bool(prefix+"memcache-enabled", false, "")
 	fs.Duration(prefix+"memcache-default-duration", 1*time.Minute, "")
+	fs.Int(prefix+"memcache-chunk-size", DefaultMemcacheChunkSize, "values larger than this are split into chunks, 0 disables chunking")
 	return fs
 }
 func NewMemcacheFromFlags(prefix string) *MemCache {
-	return NewMemcache(memcache.New(viper.GetStringSlice(prefix+"memcache-addrs")...), viper.GetDuration(prefix+"memcache-default-duration"), prefix, viper.GetBool(prefix+"memcache-enabled"))
+	c := NewMemcache(memcache.New(viper.GetStringSlice(prefix+"memcache-addrs")...), viper.GetDuration(prefix+"memcache-default-duration"), prefix, viper.GetBool(prefix+"memcache-enabled"))
+	c.SetChunkSize(viper.GetInt(prefix + "memcache-chunk-size"))
+	return c
 }
 
 func NewMemcache(cacher *memcache.Client, defaultDuration time.Duration, instance string, enabled bool) *MemCache {
-	return &MemCache{
+	tags := NewCacheTags("memcache", instance)
+	c := &MemCache{
 		memcacheClient:  cacher,
 		defaultDuration: defaultDuration,
-		cacheTags:       NewCacheTags("memcache", instance),
+		cacheTags:       tags,
+		interceptors:    interceptorChain{MetricsInterceptor(tags), TracingInterceptor(tags.CacheName)},
 		enabled:         enabled,
 	}
+	c.chunkSize.Store(DefaultMemcacheChunkSize)
+	return c
+}
+
+// SetChunkSize sets the size above which values are stored as chunks, 0 disables chunking.
+// It is safe to call while the cache is in use.
+func (c *MemCache) SetChunkSize(size int) {
+	c.chunkSize.Store(int64(size))
 }
 
 func (c *MemCache) GetName() string {
@@ -53,15 +72,41 @@ func (c *MemCache) Close() {
 
 }
 
+// Ping round trips a get for a key that is never set, a miss means the servers answered.
 func (c *MemCache) Ping(ctx context.Context) error {
-	return nil
+	if !c.enabled {
+		return nil
+	}
+	_, err := c.memcacheClient.Get(ctx, "cachec_ping")
+	if err == nil || errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+	return err
+}
+
+// memcacheExpiration rounds sub second timeouts up, memcache treats 0 as never expiring.
+func memcacheExpiration(cacheTimeout time.Duration) int32 {
+	if cacheTimeout <= 0 {
+		return 0
+	}
+	if cacheTimeout < time.Second {
+		return 1
+	}
+	return int32(cacheTimeout.Seconds())
 }
 
 func (c *MemCache) DeleteKey(ctx context.Context, key string) error {
 	if !c.enabled {
 		return nil
 	}
-	return c.memcacheClient.Delete(ctx, key)
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdDELETE, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		// the chunks of a chunked value expire with it, reading the manifest would cost every delete a round trip.
+		err := c.memcacheClient.Delete(ctx, op.Key)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return nil
+		}
+		return err
+	})
 }
 
 func (c *MemCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
@@ -75,58 +120,92 @@ func (c *MemCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time
 	if !c.enabled {
 		return nil
 	}
-	var cacheErr error
-	s := c.cacheTags.record(ctx, CacheCmdSET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
-		}
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdSET, Cache: c.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		data, err := encodeItem(item)
 		if err != nil {
-			return CacheStatusERR
+			return err
 		}
-		return CacheStatusOK
-	})
-	defer func() {
-		s(cacheErr)
-	}()
-	data, err := json.Marshal(item)
-	if err != nil {
-		cacheErr = err
-		return err
-	}
-	cacheErr = c.memcacheClient.Set(ctx, &memcache.Item{
-		Key:        key,
-		Value:      data,
-		Expiration: int32(cacheTimeout.Seconds()),
+		op.Size = len(data)
+		size := int(c.chunkSize.Load())
+		if size <= 0 || len(data) <= size {
+			// a chunked value being replaced leaves its chunks to expire, small writes stay a single round trip.
+			return c.memcacheClient.Set(ctx, &memcache.Item{
+				Key:        op.Key,
+				Value:      data,
+				Expiration: memcacheExpiration(cacheTimeout),
+			})
+		}
+		// large values usually replace large values, their chunks are deleted once the new value is stored.
+		previous, _ := c.manifest(ctx, op.Key)
+		if err := c.setChunks(ctx, op.Key, data, size, memcacheExpiration(cacheTimeout)); err != nil || previous == nil {
+			return err
+		}
+		c.deleteChunks(ctx, op.Key, previous)
+		return nil
 	})
-	return cacheErr
 }
 
 func (c *MemCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
 	if !c.enabled {
 		return nil, ErrCacheMiss
 	}
-	var cacheErr error
-	s := c.cacheTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
+	var output []byte
+	err := c.interceptors.run(ctx, &Operation{Cmd: CacheCmdGET, Cache: c.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		it, err := c.memcacheClient.Get(ctx, op.Key)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return ErrCacheMiss
 		}
 		if err != nil {
-			return CacheStatusERR
+			return err
+		}
+		output = it.Value
+		if it.Flags&memcacheChunkFlag != 0 {
+			output, err = c.getChunks(ctx, op.Key, it.Value)
+			if err != nil {
+				return err
+			}
 		}
-		return CacheStatusFOUND
+		op.Size = len(output)
+		return nil
 	})
-	defer func() {
-		s(cacheErr)
-	}()
-
-	it, err := c.memcacheClient.Get(ctx, key)
-	if errors.Is(err, memcache.ErrCacheMiss) {
-		cacheErr = ErrCacheMiss
-		return nil, ErrCacheMiss
-	}
 	if err != nil {
-		cacheErr = err
 		return nil, err
 	}
-	return it.Value, nil
+	return output, nil
+}
+
+// TTL is not supported, memcache does not expose the expiration of an item.
+func (c *MemCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return 0, ErrNotSupported
+}
+
+func (c *MemCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultDuration
+	}
+	return c.touch(ctx, key, memcacheExpiration(ttl))
+}
+
+func (c *MemCache) Persist(ctx context.Context, key string) error {
+	return c.touch(ctx, key, 0)
+}
+
+func (c *MemCache) touch(ctx context.Context, key string, seconds int32) error {
+	if !c.enabled {
+		return ErrCacheMiss
+	}
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		err := c.memcacheClient.Touch(ctx, op.Key, seconds)
+		if err == nil {
+			if m, _ := c.manifest(ctx, op.Key); m != nil {
+				for _, k := range m.keys(op.Key) {
+					err = multierr.Combine(err, c.memcacheClient.Touch(ctx, k, seconds))
+				}
+			}
+		}
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return ErrCacheMiss
+		}
+		return err
+	})
 }
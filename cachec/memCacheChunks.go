@@ -0,0 +1,120 @@
+package cachec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/Seann-Moser/cutil/logc"
+	"github.com/google/uuid"
+	"github.com/orijtech/gomemcache/memcache"
+	"go.uber.org/zap"
+)
+
+// memcacheChunkFlag marks an item whose value is a chunk manifest.
+const memcacheChunkFlag uint32 = 1 << 8
+
+// chunkManifest is written after its chunks, a value with a missing or mismatching chunk is a miss.
+type chunkManifest struct {
+	ID       string `json:"id"`
+	Chunks   int    `json:"chunks"`
+	Size     int    `json:"size"`
+	Checksum uint32 `json:"checksum"`
+}
+
+func (m *chunkManifest) keys(key string) []string {
+	keys := make([]string, m.Chunks)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s:%s:%d", key, m.ID, i)
+	}
+	return keys
+}
+
+// setChunks writes the chunks under a new id before replacing the manifest, readers never see a mix of writes.
+func (c *MemCache) setChunks(ctx context.Context, key string, data []byte, chunkSize int, expiration int32) error {
+	m := &chunkManifest{
+		ID:       uuid.New().String()[:8],
+		Chunks:   (len(data) + chunkSize - 1) / chunkSize,
+		Size:     len(data),
+		Checksum: crc32.ChecksumIEEE(data),
+	}
+	for i, k := range m.keys(key) {
+		end := (i + 1) * chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		err := c.memcacheClient.Set(ctx, &memcache.Item{
+			Key:        k,
+			Value:      data[i*chunkSize : end],
+			Expiration: expiration,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.memcacheClient.Set(ctx, &memcache.Item{
+		Key:        key,
+		Value:      value,
+		Flags:      memcacheChunkFlag,
+		Expiration: expiration,
+	})
+}
+
+// deleteChunks removes the chunks of a replaced manifest, chunks that are already gone expire with their value.
+func (c *MemCache) deleteChunks(ctx context.Context, key string, m *chunkManifest) {
+	for _, k := range m.keys(key) {
+		if err := c.memcacheClient.Delete(ctx, k); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			logc.Debug(ctx, "failed deleting replaced chunk", zap.String("key", k), zap.Error(err))
+		}
+	}
+}
+
+func (c *MemCache) getChunks(ctx context.Context, key string, manifest []byte) ([]byte, error) {
+	var m chunkManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, err
+	}
+	keys := m.keys(key)
+	items, err := c.memcacheClient.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	data := bytes.NewBuffer(make([]byte, 0, m.Size))
+	for _, k := range keys {
+		it, found := items[k]
+		if !found {
+			return nil, ErrCacheMiss
+		}
+		data.Write(it.Value)
+	}
+	if data.Len() != m.Size || crc32.ChecksumIEEE(data.Bytes()) != m.Checksum {
+		return nil, ErrCacheMiss
+	}
+	return data.Bytes(), nil
+}
+
+// manifest returns the chunk manifest stored at key, nil if the value is not chunked.
+func (c *MemCache) manifest(ctx context.Context, key string) (*chunkManifest, error) {
+	it, err := c.memcacheClient.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if it.Flags&memcacheChunkFlag == 0 {
+		return nil, nil
+	}
+	var m chunkManifest
+	if err := json.Unmarshal(it.Value, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,57 @@
+package cachec_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orijtech/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/cachec/cachectest"
+)
+
+func TestMemcacheChunking(t *testing.T) {
+	ctx := context.Background()
+	s := cachectest.StartMemcache(t)
+	client := memcache.New(s.Addr())
+	c := cachec.NewMemcache(client, time.Minute, "chunks", true)
+
+	large := bytes.Repeat([]byte("0123456789"), 350*1000)
+	assert.NoError(t, c.SetCache(ctx, "", "large", large))
+	assert.Equal(t, 5, s.Len())
+	v, err := c.GetCache(ctx, "", "large")
+	assert.NoError(t, err)
+	assert.Equal(t, large, v)
+
+	assert.NoError(t, c.SetCache(ctx, "", "small", []byte("small")))
+	v, err = c.GetCache(ctx, "", "small")
+	assert.NoError(t, err)
+	assert.Equal(t, "small", string(v))
+
+	assert.NoError(t, c.SetCache(ctx, "", "large", large))
+	assert.Equal(t, 6, s.Len(), "chunked overwrites delete the chunks of the previous value")
+	assert.NoError(t, c.SetCache(ctx, "", "large", []byte("small again")))
+	assert.Equal(t, 6, s.Len(), "small overwrites leave the chunks to expire")
+	v, err = c.GetCache(ctx, "", "large")
+	assert.NoError(t, err)
+	assert.Equal(t, "small again", string(v))
+
+	assert.NoError(t, c.DeleteKey(ctx, "large"))
+	assert.Equal(t, 5, s.Len())
+
+	c.SetChunkSize(4)
+	assert.NoError(t, c.SetCache(ctx, "", "partial", []byte("a partially written value")))
+	item, err := client.Get(ctx, "partial")
+	assert.NoError(t, err)
+	assert.NoError(t, client.Set(ctx, &memcache.Item{Key: "partial", Value: bytes.Replace(item.Value, []byte(`"chunks":7`), []byte(`"chunks":8`), 1), Flags: item.Flags}))
+	_, err = c.GetCache(ctx, "", "partial")
+	assert.ErrorIs(t, err, cachec.ErrCacheMiss)
+
+	c.SetChunkSize(0)
+	_, err = client.Get(ctx, "large")
+	assert.ErrorIs(t, err, memcache.ErrCacheMiss)
+	assert.Error(t, c.SetCache(ctx, "", "large", large))
+}
@@ -0,0 +1,199 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrFlightPanic is returned to the callers sharing a load when the load panicked.
+var ErrFlightPanic = errors.New("shared cache load panicked")
+
+type memoizer[K comparable] struct {
+	client       *Client
+	group        string
+	key          func(key K) string
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	negative     []error
+	singleflight bool
+	flight       *flightGroup
+}
+
+type MemoizeOption[K comparable] func(m *memoizer[K])
+
+// WithMemoizeGroup sets the cache group, by default the name of the memoized function.
+func WithMemoizeGroup[K comparable](group string) MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.group = group
+	}
+}
+
+// WithMemoizeKey derives the cache key from the argument, by default fmt.Sprintf("%v", key).
+func WithMemoizeKey[K comparable](key func(key K) string) MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.key = key
+	}
+}
+
+func WithMemoizeTTL[K comparable](ttl time.Duration) MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.ttl = ttl
+	}
+}
+
+// WithNegativeCache caches errors matching one of errs for ttl, cache hits return the matching error.
+func WithNegativeCache[K comparable](ttl time.Duration, errs ...error) MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.negativeTTL = ttl
+		m.negative = append(m.negative, errs...)
+	}
+}
+
+// WithSingleflight shares one call of the function between concurrent misses for the same key.
+func WithSingleflight[K comparable]() MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.singleflight = true
+	}
+}
+
+func WithMemoizeClient[K comparable](client *Client) MemoizeOption[K] {
+	return func(m *memoizer[K]) {
+		m.client = client
+	}
+}
+
+type memoEntry[V any] struct {
+	Value V      `json:"value"`
+	Err   string `json:"err,omitempty"`
+}
+
+// Memoize returns fn backed by the cache, replacing hand written GetSet closures.
+func Memoize[K comparable, V any](fn func(ctx context.Context, key K) (V, error), opts ...MemoizeOption[K]) func(ctx context.Context, key K) (V, error) {
+	m := &memoizer[K]{
+		group: runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name(),
+		key: func(key K) string {
+			return fmt.Sprintf("%v", key)
+		},
+		flight: newFlightGroup(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	name := "memoize_" + typeName[V]()
+
+	load := func(ctx context.Context, c *Client, key K, k string) (V, error) {
+		v, err := fn(ctx, key)
+		if err != nil {
+			if neg := m.match(err); neg != nil && m.negativeTTL > 0 {
+				_ = c.set(ctx, c.Cache(ctx), name, m.group, k, memoEntry[V]{Err: neg.Error()}, m.negativeTTL, true)
+			}
+			return v, err
+		}
+		_ = c.set(ctx, c.Cache(ctx), name, m.group, k, memoEntry[V]{Value: v}, m.ttl, m.ttl > 0)
+		return v, nil
+	}
+
+	return func(ctx context.Context, key K) (V, error) {
+		c := m.client
+		if c == nil {
+			c = Default()
+		}
+		k := m.key(key)
+		var entry memoEntry[V]
+		if err := c.get(ctx, c.Cache(ctx), name, m.group, k, &entry); err == nil {
+			if entry.Err != "" {
+				return entry.Value, m.lookup(entry.Err)
+			}
+			return entry.Value, nil
+		}
+		if !m.singleflight {
+			return load(ctx, c, key, k)
+		}
+		// the flight is keyed like the cache entry, so callers of different tenants never share a load.
+		fk, err := c.KeyCtx(ctx, name, m.group, k)
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		v, err := m.flight.do(ctx, fk, func(ctx context.Context) (interface{}, error) {
+			return load(ctx, c, key, k)
+		})
+		out, _ := v.(V)
+		return out, err
+	}
+}
+
+func (m *memoizer[K]) match(err error) error {
+	for _, e := range m.negative {
+		if errors.Is(err, e) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *memoizer[K]) lookup(msg string) error {
+	for _, e := range m.negative {
+		if e.Error() == msg {
+			return e
+		}
+	}
+	return errors.New(msg)
+}
+
+type flightGroup struct {
+	mutex *sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		mutex: &sync.Mutex{},
+		calls: map[string]*flightCall{},
+	}
+}
+
+// do runs fn once per key at a time, concurrent callers wait for and share the result. Keys must be scoped like cache
+// keys, including the tenant and its generation, or callers of different tenants share results.
+// fn runs on a context detached from the cancellation of the callers, so each caller stops waiting when its own
+// ctx is done without failing the others. A panic in fn is returned to every caller as ErrFlightPanic.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mutex.Lock()
+	call, found := g.calls[key]
+	if !found {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(context.WithoutCancel(ctx), key, call, fn)
+	}
+	g.mutex.Unlock()
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if p := recover(); p != nil {
+			call.value, call.err = nil, fmt.Errorf("%w: %v", ErrFlightPanic, p)
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn(ctx)
+}
@@ -0,0 +1,141 @@
+package cachec
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoize(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	var calls atomic.Int32
+	release := make(chan struct{})
+	find := Memoize(func(ctx context.Context, id int) (string, error) {
+		calls.Add(1)
+		<-release
+		if id < 0 {
+			return "", sql.ErrNoRows
+		}
+		return "user-" + strconv.Itoa(id), nil
+	},
+		WithMemoizeClient[int](client),
+		WithMemoizeTTL[int](time.Minute),
+		WithNegativeCache[int](time.Minute, sql.ErrNoRows),
+		WithSingleflight[int](),
+	)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := find(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", v)
+		}()
+	}
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	v, err := find(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", v)
+	assert.Equal(t, int32(1), calls.Load())
+
+	for i := 0; i < 2; i++ {
+		_, err = find(ctx, -1)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMemoizeTenants(t *testing.T) {
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	find := Memoize(func(ctx context.Context, id int) (string, error) {
+		started <- struct{}{}
+		<-release
+		return TenantFromContext(ctx) + "-" + strconv.Itoa(id), nil
+	},
+		WithMemoizeClient[int](client),
+		WithSingleflight[int](),
+	)
+
+	wg := &sync.WaitGroup{}
+	results := make([]string, 2)
+	for i, tenant := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := find(ContextWithTenant(context.Background(), tenant), 1)
+			assert.NoError(t, err)
+			results[i] = v
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Error("tenants share a load")
+		}
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"a-1", "b-1"}, results)
+}
+
+func TestFlightGroup_Panic(t *testing.T) {
+	g := newFlightGroup()
+	_, err := g.do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	assert.ErrorIs(t, err, ErrFlightPanic)
+
+	v, err := g.do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	})
+	assert.NoError(t, err, "a panic does not wedge the key")
+	assert.Equal(t, "value", v)
+}
+
+func TestFlightGroup_CallerContext(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.do(first, "key", func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return "value", ctx.Err()
+		})
+		errs <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled, "the first caller leaves on its own ctx")
+
+	waiter := make(chan interface{}, 1)
+	go func() {
+		v, err := g.do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+			return "second", nil
+		})
+		assert.NoError(t, err)
+		waiter <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, "value", <-waiter, "waiters share the load of the cancelled caller")
+}
@@ -3,11 +3,16 @@ package cachec
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 )
 
 type CacheStatus string
@@ -17,6 +22,8 @@ const (
 	CacheCmdSET    = CacheCmd("SET")
 	CacheCmdGET    = CacheCmd("GET")
 	CacheCmdDELETE = CacheCmd("DELETE")
+	CacheCmdTTL    = CacheCmd("TTL")
+	CacheCmdTOUCH  = CacheCmd("TOUCH")
 
 	CacheStatusFOUND   = CacheStatus("FOUND")
 	CacheStatusOK      = CacheStatus("OK")
@@ -24,6 +31,73 @@ const (
 	CacheStatusERR     = CacheStatus("ERR")
 )
 
+const (
+	meterName = "github.com/Seann-Moser/cutil/cachec"
+
+	MetricLatency = "cachec.client.latency"
+	MetricCalls   = "cachec.client.calls"
+
+	AttrStatus = attribute.Key("cache.status")
+)
+
+var (
+	metricsMutex     = &sync.RWMutex{}
+	meterProvider    metric.MeterProvider
+	openCensusBridge = true
+
+	latencyBuckets = []float64{
+		0.0, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 1.5, 2.0, 2.5, 5.0, 10.0, 25.0, 50.0, 100.0, 200.0, 400.0,
+		600.0, 800.0, 1000.0, 1500.0, 2000.0, 2500.0, 5000.0, 10000.0, 20000.0, 40000.0, 100000.0, 200000.0, 500000.0,
+	}
+)
+
+type metricAttrsCtxName string
+
+const metricAttrsCtx = metricAttrsCtxName("metric-attributes")
+
+// SetMeterProvider sets the provider of the cache and ORM instruments, nil falls back to the global provider.
+// Caches keep the instruments of the provider set when they were created.
+func SetMeterProvider(mp metric.MeterProvider) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	meterProvider = mp
+}
+
+func MeterProvider() metric.MeterProvider {
+	metricsMutex.RLock()
+	defer metricsMutex.RUnlock()
+	if meterProvider == nil {
+		return otel.GetMeterProvider()
+	}
+	return meterProvider
+}
+
+// SetOpenCensusBridge turns recording to the OpenCensus views on or off.
+// It is on by default so dashboards built on the <cache>.cache/client views keep working while they move to the
+// OpenTelemetry instruments.
+func SetOpenCensusBridge(enabled bool) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	openCensusBridge = enabled
+}
+
+func OpenCensusBridgeEnabled() bool {
+	metricsMutex.RLock()
+	defer metricsMutex.RUnlock()
+	return openCensusBridge
+}
+
+// ContextWithMetricAttributes adds attributes to every cache metric recorded with ctx.
+func ContextWithMetricAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	return context.WithValue(ctx, metricAttrsCtx, append(MetricAttributes(ctx), attrs...))
+}
+
+func MetricAttributes(ctx context.Context) []attribute.KeyValue {
+	attrs, _ := ctx.Value(metricAttrsCtx).([]attribute.KeyValue)
+	return attrs[:len(attrs):len(attrs)]
+}
+
+// CacheTags records the latency and calls of a cache. The tag keys and measure feed the OpenCensus bridge.
 type CacheTags struct {
 	CacheName string
 	instance  string
@@ -31,6 +105,9 @@ type CacheTags struct {
 	Status    tag.Key
 	Cmd       tag.Key
 	Latency   *stats.Int64Measure
+
+	latency metric.Float64Histogram
+	calls   metric.Int64Counter
 }
 
 func NewCacheTags(cacheName string, instance string) CacheTags {
@@ -42,7 +119,23 @@ func NewCacheTags(cacheName string, instance string) CacheTags {
 		Cmd:       tag.MustNewKey(fmt.Sprintf("%s_cache_cmd", cacheName)),
 		Latency:   stats.Int64(fmt.Sprintf("%s.cache/latency", cacheName), "latency of calls in milliseconds", stats.UnitMilliseconds),
 	}
-	_ = tags.RegisterAllViews()
+	meter := MeterProvider().Meter(meterName)
+	var err error
+	tags.latency, err = meter.Float64Histogram(MetricLatency,
+		metric.WithDescription("The distribution of latency of various calls in milliseconds"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(latencyBuckets...),
+	)
+	if err != nil {
+		tags.latency, _ = noop.NewMeterProvider().Meter(meterName).Float64Histogram(MetricLatency)
+	}
+	tags.calls, err = meter.Int64Counter(MetricCalls, metric.WithDescription("The number of various calls of methods"))
+	if err != nil {
+		tags.calls, _ = noop.NewMeterProvider().Meter(meterName).Int64Counter(MetricCalls)
+	}
+	if OpenCensusBridgeEnabled() {
+		_ = tags.RegisterAllViews()
+	}
 	return tags
 }
 
@@ -57,40 +150,8 @@ func (c *CacheTags) Views() []*view.View {
 		Name:        formatedViewName + "/latency",
 		Description: "The distribution of latency of various calls in milliseconds",
 		Measure:     c.Latency,
-		Aggregation: view.Distribution(
-			0.0,
-			0.001,
-			0.005,
-			0.01,
-			0.05,
-			0.1,
-			0.5,
-			1.0,
-			1.5,
-			2.0,
-			2.5,
-			5.0,
-			10.0,
-			25.0,
-			50.0,
-			100.0,
-			200.0,
-			400.0,
-			600.0,
-			800.0,
-			1000.0,
-			1500.0,
-			2000.0,
-			2500.0,
-			5000.0,
-			10000.0,
-			20000.0,
-			40000.0,
-			100000.0,
-			200000.0,
-			500000.0,
-		),
-		TagKeys: []tag.Key{c.Cmd, c.Status, c.Name},
+		Aggregation: view.Distribution(latencyBuckets...),
+		TagKeys:     []tag.Key{c.Cmd, c.Status, c.Name},
 	}
 
 	callsView := &view.View{
@@ -110,13 +171,27 @@ func (c *CacheTags) record(ctx context.Context, cmd CacheCmd, status Status) fun
 	var startTime = time.Now()
 	return func(err error) {
 		var (
-			timeSpentMs = time.Since(startTime).Milliseconds()
-			tags        = []tag.Mutator{
-				tag.Insert(c.Name, c.instance),
-				tag.Insert(c.Cmd, string(cmd)),
-				tag.Insert(c.Status, string(status(err))),
-			}
+			spent    = time.Since(startTime)
+			statusOf = status(err)
+			attrs    = metric.WithAttributes(append(MetricAttributes(ctx),
+				AttrBackend.String(c.CacheName),
+				AttrCache.String(c.instance),
+				AttrCmd.String(string(cmd)),
+				AttrStatus.String(string(statusOf)),
+			)...)
 		)
-		_ = stats.RecordWithTags(ctx, tags, c.Latency.M(timeSpentMs))
+		if c.latency != nil {
+			c.latency.Record(ctx, float64(spent)/float64(time.Millisecond), attrs)
+			c.calls.Add(ctx, 1, attrs)
+		}
+		if !OpenCensusBridgeEnabled() || c.Latency == nil {
+			return
+		}
+		tags := []tag.Mutator{
+			tag.Insert(c.Name, c.instance),
+			tag.Insert(c.Cmd, string(cmd)),
+			tag.Insert(c.Status, string(statusOf)),
+		}
+		_ = stats.RecordWithTags(ctx, tags, c.Latency.M(spent.Milliseconds()))
 	}
 }
@@ -0,0 +1,59 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	rm := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	return nil
+}
+
+func TestCacheMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer SetMeterProvider(nil)
+
+	ctx := ContextWithMetricAttributes(context.Background(), attribute.String("query_name", "users"))
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "metrics")
+	_, err := c.GetCache(ctx, "group", "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.NoError(t, c.SetCache(ctx, "group", "key", "value"))
+	_, err = c.GetCache(ctx, "group", "key")
+	assert.NoError(t, err)
+
+	calls, ok := collectMetric(t, reader, MetricCalls).(metricdata.Sum[int64])
+	assert.True(t, ok)
+	statuses := map[string]int64{}
+	for _, dp := range calls.DataPoints {
+		backend, _ := dp.Attributes.Value(AttrBackend)
+		assert.Equal(t, "go-cache", backend.AsString())
+		query, _ := dp.Attributes.Value("query_name")
+		assert.Equal(t, "users", query.AsString())
+		status, _ := dp.Attributes.Value(AttrStatus)
+		statuses[status.AsString()] += dp.Value
+	}
+	assert.Equal(t, map[string]int64{"MISSING": 1, "OK": 1, "FOUND": 1}, statuses)
+
+	latency, ok := collectMetric(t, reader, MetricLatency).(metricdata.Histogram[float64])
+	assert.True(t, ok)
+	assert.Len(t, latency.DataPoints, 3)
+	assert.NotNil(t, view.Find("go-cache.cache/client/calls"))
+}
@@ -2,6 +2,7 @@ package cachec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Seann-Moser/cutil/logc"
 	"github.com/google/uuid"
@@ -16,15 +17,27 @@ var GlobalCacheMonitor CacheMonitor = NewMonitor()
 
 const GroupPrefix = "[CTX_CACHE_GROUP]"
 
+const (
+	brokerRetryMin = 100 * time.Millisecond
+	brokerRetryMax = 30 * time.Second
+)
+
+// ErrPublish wraps the broker errors of Publish, the change itself has been applied and delivered to local watchers.
+var ErrPublish = errors.New("failed publishing cache event")
+
 type CacheMonitor interface {
 	AddGroupKeys(ctx context.Context, group string, newKeys ...string) error
 	HasGroupKeyBeenUpdated(ctx context.Context, group string) bool
+	LastUpdated(ctx context.Context, group string) (time.Time, error)
 	GetGroupKeys(ctx context.Context, group string) (map[string]struct{}, error)
 	DeleteCache(ctx context.Context, group string) error
 	UpdateCache(ctx context.Context, group string, key string) error
+	MarkUpdated(ctx context.Context, group string) error
 	WaitForTransaction(ctx context.Context, group string, read bool)
 	StartTransaction(ctx context.Context, group string, duration time.Duration, read bool) (string, context.Context, context.CancelFunc)
 	EndTransaction(ctx context.Context, id string, read bool)
+	Watch(ctx context.Context, group string) (<-chan Event, error)
+	Publish(ctx context.Context, event Event) error
 }
 
 type CacheMonitorImpl struct {
@@ -34,6 +47,11 @@ type CacheMonitorImpl struct {
 
 	txMutex            *sync.RWMutex
 	transactionMonitor map[string]*TransactionMonitor
+
+	id          string
+	watchers    *watchers
+	brokerMutex *sync.RWMutex
+	broker      EventBroker
 }
 
 type TransactionMonitor struct {
@@ -48,37 +66,127 @@ func NewMonitor() CacheMonitor {
 		Mutex:              &sync.RWMutex{},
 		txMutex:            &sync.RWMutex{},
 		transactionMonitor: make(map[string]*TransactionMonitor),
+		id:                 uuid.New().String(),
+		watchers:           newWatchers(),
+		brokerMutex:        &sync.RWMutex{},
+	}
+}
+
+// UseBroker publishes local events through the broker and delivers events from other processes to local watchers.
+// The subscription is retried with exponential backoff until ctx is done.
+func (c *CacheMonitorImpl) UseBroker(ctx context.Context, broker EventBroker) {
+	c.brokerMutex.Lock()
+	c.broker = broker
+	c.brokerMutex.Unlock()
+	go func() {
+		backoff := brokerRetryMin
+		for {
+			started := time.Now()
+			err := broker.Subscribe(ctx, func(event Event) {
+				if event.Source == c.id {
+					return
+				}
+				c.watchers.dispatch(event)
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			// a subscription that lasted longer than the backoff cap was healthy, start over.
+			if time.Since(started) > brokerRetryMax {
+				backoff = brokerRetryMin
+			}
+			logc.Error(ctx, "cache event broker stopped, resubscribing", zap.Error(err), zap.Duration("backoff", backoff))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, brokerRetryMax)
+		}
+	}()
+}
+
+func (c *CacheMonitorImpl) Watch(ctx context.Context, group string) (<-chan Event, error) {
+	if group != "" {
+		group = TenantGroup(ctx, group)
+	}
+	return c.watchers.watch(ctx, group), nil
+}
+
+func (c *CacheMonitorImpl) Publish(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
 	}
+	if event.Source == "" {
+		event.Source = c.id
+	}
+	c.watchers.dispatch(event)
+	c.brokerMutex.RLock()
+	broker := c.broker
+	c.brokerMutex.RUnlock()
+	if broker == nil {
+		return nil
+	}
+	if err := broker.Publish(ctx, event); err != nil {
+		return fmt.Errorf("%w: %w", ErrPublish, err)
+	}
+	return nil
 }
 
+// UpdateCache records key in the group and marks the group as updated, group names are scoped to the tenant in ctx.
 func (c *CacheMonitorImpl) UpdateCache(ctx context.Context, group string, key string) error {
-	err := c.AddGroupKeys(ctx, group, key)
+	group = TenantGroup(ctx, group)
+	err := c.addGroupKeys(ctx, group, key)
 	if err != nil {
 		return err
 	}
-	now := time.Now()
-	groupKey := fmt.Sprintf("%s_%s_updated", GroupPrefix, group)
-	c.setGroupKeys(groupKey, now.Unix())
-	err = SetWithExpiration[int64](ctx, 60*time.Minute, GroupPrefix, groupKey, now.Unix())
+	now, err := c.markUpdated(ctx, group)
 	if err != nil {
 		return err
 	}
 	logc.Debug(ctx, "setting cache", zap.String("group", group), zap.String("key", key))
-	return nil
+	return c.Publish(ctx, Event{Type: EventSet, Group: group, Key: key, Time: now})
+}
+
+// MarkUpdated marks the group as updated without recording a key or publishing an event, for changes to the whole
+// group that publish their own event.
+func (c *CacheMonitorImpl) MarkUpdated(ctx context.Context, group string) error {
+	_, err := c.markUpdated(ctx, TenantGroup(ctx, group))
+	return err
+}
+
+// markUpdated stores the update time of the tenant scoped group.
+func (c *CacheMonitorImpl) markUpdated(ctx context.Context, group string) (time.Time, error) {
+	now := time.Now()
+	groupKey := fmt.Sprintf("%s_%s_updated", GroupPrefix, group)
+	c.setGroupKeys(groupKey, now.UnixNano())
+	return now, SetWithExpiration[int64](ctx, 60*time.Minute, GroupPrefix, groupKey, now.UnixNano())
 }
 
 func (c *CacheMonitorImpl) DeleteCache(ctx context.Context, group string) error {
-	keys, err := c.GetGroupKeys(ctx, group)
-	if err != nil {
+	group = TenantGroup(ctx, group)
+	// caches using the group key layout find the entries themselves, the key record may have expired.
+	gc, scan := groupCache(GetCacheFromContext(ctx))
+	keys, err := c.getGroupKeys(ctx, group)
+	if err != nil && !scan {
 		return err
 	}
+	err = nil
 	for k := range keys {
 		err = multierr.Combine(err, DeleteKey(ctx, k))
 	}
-	return err
+	if scan {
+		_, e := gc.DeleteGroup(ctx, group)
+		err = multierr.Combine(err, e)
+	}
+	return multierr.Combine(err, c.Publish(ctx, Event{Type: EventFlush, Group: group}))
 }
 
 func (c *CacheMonitorImpl) GetGroupKeys(ctx context.Context, group string) (map[string]struct{}, error) {
+	return c.getGroupKeys(ctx, TenantGroup(ctx, group))
+}
+
+func (c *CacheMonitorImpl) getGroupKeys(ctx context.Context, group string) (map[string]struct{}, error) {
 	key := fmt.Sprintf("%s_%s_keys", GroupPrefix, group)
 	keys, err := Get[map[string]struct{}](ctx, GroupPrefix, key)
 	var foundKeys map[string]struct{}
@@ -91,6 +199,10 @@ func (c *CacheMonitorImpl) GetGroupKeys(ctx context.Context, group string) (map[
 }
 
 func (c *CacheMonitorImpl) AddGroupKeys(ctx context.Context, group string, newKeys ...string) error {
+	return c.addGroupKeys(ctx, TenantGroup(ctx, group), newKeys...)
+}
+
+func (c *CacheMonitorImpl) addGroupKeys(ctx context.Context, group string, newKeys ...string) error {
 	if len(newKeys) == 0 {
 		return nil
 	}
@@ -119,11 +231,11 @@ func (c *CacheMonitorImpl) HasGroupKeyBeenUpdated(ctx context.Context, group str
 	if group == GroupPrefix {
 		return false
 	}
-	key := fmt.Sprintf("%s_%s_updated", GroupPrefix, group)
+	key := fmt.Sprintf("%s_%s_updated", GroupPrefix, TenantGroup(ctx, group))
 	lastUpdated, err := Get[int64](ctx, GroupPrefix, key)
 	if err != nil {
 		logc.Debug(ctx, "failed getting last updated group", zap.Error(err))
-		err = SetWithExpiration[int64](ctx, 60*time.Minute, GroupPrefix, key, time.Now().Unix())
+		err = SetWithExpiration[int64](ctx, 60*time.Minute, GroupPrefix, key, time.Now().UnixNano())
 		if err != nil {
 			return true
 		}
@@ -146,6 +258,23 @@ func (c *CacheMonitorImpl) HasGroupKeyBeenUpdated(ctx context.Context, group str
 	return true
 }
 
+// LastUpdated returns when the group was last updated, the zero time if no update has been recorded.
+func (c *CacheMonitorImpl) LastUpdated(ctx context.Context, group string) (time.Time, error) {
+	key := fmt.Sprintf("%s_%s_updated", GroupPrefix, TenantGroup(ctx, group))
+	lastUpdated, err := Get[int64](ctx, GroupPrefix, key)
+	if errors.Is(err, ErrCacheMiss) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	// records written before updates were kept in nanoseconds are in seconds.
+	if *lastUpdated < 1e12 {
+		return time.Unix(*lastUpdated, 0), nil
+	}
+	return time.Unix(0, *lastUpdated), nil
+}
+
 func (c *CacheMonitorImpl) setGroupKeys(key string, lastUpdated int64) {
 	c.Mutex.Lock()
 	defer c.Mutex.Unlock()
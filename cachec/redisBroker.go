@@ -0,0 +1,60 @@
+package cachec
+
+import (
+	"context"
+	"encoding/json"
+
+	redis "github.com/Seann-Moser/ociredis"
+)
+
+const DefaultEventChannel = "cachec_events"
+
+var _ EventBroker = &RedisBroker{}
+
+// RedisBroker shares cache events between processes over redis pub/sub.
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	if channel == "" {
+		channel = DefaultEventChannel
+	}
+	return &RedisBroker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (r *RedisBroker) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return r.client.WithContext(ctx).Publish(r.channel, data).Err()
+}
+
+func (r *RedisBroker) Subscribe(ctx context.Context, fn func(event Event)) error {
+	ps := r.client.Subscribe(r.channel)
+	defer ps.Close()
+	if _, err := ps.Receive(); err != nil {
+		return err
+	}
+	messages := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			fn(event)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package cachec
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -13,12 +12,18 @@ import (
 )
 
 var _ Cache = &RedisCache{}
+var _ GroupCache = &RedisCache{}
+
+const DefaultRedisScanBatch = 500
 
 type RedisCache struct {
 	cacher          *redis.Client
 	defaultDuration time.Duration
 	cacheTags       CacheTags
+	interceptors    interceptorChain
 	enabled         bool
+	groupKeys       bool
+	scanBatch       int
 }
 
 func (c *RedisCache) GetParentCaches() map[string]Cache {
@@ -32,6 +37,8 @@ func RedisFlags(prefix string) *pflag.FlagSet {
 	fs.Bool(prefix+"redis-enabled", false, "")
 	fs.String(prefix+"redis-instance", "default", "")
 	fs.Duration(prefix+"redis-cleanup-duration", 1*time.Minute, "")
+	fs.Bool(prefix+"redis-group-keys", false, "start keys with their group so groups can be deleted with SCAN")
+	fs.Int(prefix+"redis-scan-batch", DefaultRedisScanBatch, "keys scanned and unlinked per batch")
 
 	return fs
 }
@@ -42,16 +49,21 @@ func NewRedisCacheFromFlags(ctx context.Context, prefix string) *RedisCache {
 		Context:  ctx,
 	})
 
-	return NewRedisCache(rdb, viper.GetDuration(prefix+"redis-cleanup-duration"), viper.GetString(prefix+"redis-instance"), viper.GetBool(prefix+"redis-enabled"))
+	c := NewRedisCache(rdb, viper.GetDuration(prefix+"redis-cleanup-duration"), viper.GetString(prefix+"redis-instance"), viper.GetBool(prefix+"redis-enabled"))
+	c.SetGroupKeys(viper.GetBool(prefix + "redis-group-keys"))
+	c.SetScanBatch(viper.GetInt(prefix + "redis-scan-batch"))
+	return c
 }
 
 func NewRedisCache(cacher *redis.Client, defaultDuration time.Duration, instance string, enabled bool) *RedisCache {
-
+	tags := NewCacheTags("redis", instance)
 	return &RedisCache{
 		cacher:          cacher,
 		defaultDuration: defaultDuration,
-		cacheTags:       NewCacheTags("redis", instance),
+		cacheTags:       tags,
+		interceptors:    interceptorChain{MetricsInterceptor(tags), TracingInterceptor(tags.CacheName)},
 		enabled:         enabled,
+		scanBatch:       DefaultRedisScanBatch,
 	}
 }
 func (c *RedisCache) Close() {
@@ -61,32 +73,19 @@ func (c *RedisCache) GetName() string {
 	return fmt.Sprintf("REDISCACHE_%s", c.cacheTags.instance)
 }
 func (c *RedisCache) DeleteKey(ctx context.Context, key string) error {
-	return c.cacher.Del(key).Err()
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdDELETE, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		return c.cacher.WithContext(ctx).Del(op.Key).Err()
+	})
 }
 func (c *RedisCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
-	var cacheErr error
-	s := c.cacheTags.record(ctx, CacheCmdSET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
-		}
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdSET, Cache: c.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		data, err := encodeItem(item)
 		if err != nil {
-			return CacheStatusERR
+			return err
 		}
-		return CacheStatusOK
+		op.Size = len(data)
+		return c.cacher.WithContext(ctx).Set(op.Key, data, cacheTimeout).Err()
 	})
-	defer func() {
-		s(cacheErr)
-	}()
-
-	data, err := json.Marshal(item)
-	if err != nil {
-		cacheErr = ErrCacheMiss
-		return err
-	}
-	localClient := c.cacher.WithContext(ctx)
-	stats := localClient.Set(key, data, cacheTimeout)
-	cacheErr = stats.Err()
-	return stats.Err()
 }
 
 func (c *RedisCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
@@ -94,34 +93,82 @@ func (c *RedisCache) SetCache(ctx context.Context, group, key string, item inter
 }
 
 func (c *RedisCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
-	var cacheErr error
-	s := c.cacheTags.record(ctx, CacheCmdGET, func(err error) CacheStatus {
-		if errors.Is(err, ErrCacheMiss) {
-			return CacheStatusMISSING
+	var output []byte
+	err := c.interceptors.run(ctx, &Operation{Cmd: CacheCmdGET, Cache: c.GetName(), Group: group, Key: key}, func(ctx context.Context, op *Operation) error {
+		data, err := c.cacher.WithContext(ctx).Get(op.Key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrCacheMiss
 		}
 		if err != nil {
-			return CacheStatusERR
+			return err
 		}
-		return CacheStatusFOUND
+		if len(data) == 0 {
+			return ErrCacheMiss
+		}
+		output = data
+		op.Size = len(output)
+		return nil
 	})
-	defer func() {
-		s(cacheErr)
-	}()
-
-	localClient := c.cacher.WithContext(ctx)
-	data, err := localClient.Get(key).Bytes()
 	if err != nil {
-		cacheErr = err
 		return nil, err
 	}
-	if len(data) == 0 {
-		cacheErr = ErrCacheMiss
-		return nil, ErrCacheMiss
-	}
-	return data, nil
+	return output, nil
 }
 
 func (c *RedisCache) Ping(ctx context.Context) error {
 	localClient := c.cacher.WithContext(ctx)
 	return localClient.Ping().Err()
 }
+
+func (c *RedisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTTL, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		d, err := c.cacher.WithContext(ctx).PTTL(op.Key).Result()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d == -2*time.Millisecond:
+			return ErrCacheMiss
+		case d < 0:
+			ttl = NoExpiration
+		default:
+			ttl = d
+		}
+		return nil
+	})
+	return ttl, err
+}
+
+func (c *RedisCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultDuration
+	}
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		found, err := c.cacher.WithContext(ctx).PExpire(op.Key, ttl).Result()
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCacheMiss
+		}
+		return nil
+	})
+}
+
+func (c *RedisCache) Persist(ctx context.Context, key string) error {
+	return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdTOUCH, Cache: c.GetName(), Key: key}, func(ctx context.Context, op *Operation) error {
+		localClient := c.cacher.WithContext(ctx)
+		if _, err := localClient.Persist(op.Key).Result(); err != nil {
+			return err
+		}
+		exists, err := localClient.Exists(op.Key).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrCacheMiss
+		}
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package cachec
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+var globReplacer = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// SetGroupKeys turns on the key layout that starts keys with their group, which lets GroupKeys and DeleteGroup
+// find a group with SCAN. Entries written before it was turned on keep their old keys.
+func (c *RedisCache) SetGroupKeys(enabled bool) {
+	c.groupKeys = enabled
+}
+
+// SetScanBatch sets the number of keys scanned and unlinked per round trip, values <= 0 use DefaultRedisScanBatch.
+func (c *RedisCache) SetScanBatch(size int) {
+	if size <= 0 {
+		size = DefaultRedisScanBatch
+	}
+	c.scanBatch = size
+}
+
+func (c *RedisCache) GroupKeyLayout() bool {
+	return c.groupKeys
+}
+
+// GroupKeys returns the keys of group, it scans the keyspace so it is meant for tooling rather than request paths.
+func (c *RedisCache) GroupKeys(ctx context.Context, group string) ([]string, error) {
+	var keys []string
+	err := c.scanGroup(ctx, group, func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	})
+	return keys, err
+}
+
+// DeleteGroup unlinks the keys of group one scanned batch at a time, so neither the scan nor the deletion blocks
+// the server. It returns the number of keys removed.
+func (c *RedisCache) DeleteGroup(ctx context.Context, group string) (int, error) {
+	deleted := 0
+	err := c.scanGroup(ctx, group, func(batch []string) error {
+		return c.interceptors.run(ctx, &Operation{Cmd: CacheCmdDELETE, Cache: c.GetName(), Group: group}, func(ctx context.Context, op *Operation) error {
+			n, err := c.cacher.WithContext(ctx).Unlink(batch...).Result()
+			deleted += int(n)
+			return err
+		})
+	})
+	return deleted, err
+}
+
+func (c *RedisCache) scanGroup(ctx context.Context, group string, fn func(batch []string) error) error {
+	if group == "" {
+		return nil
+	}
+	match := globReplacer.Replace(GroupKeyPrefix(group)) + "*"
+	client := c.cacher.WithContext(ctx)
+	var cursor uint64
+	var err error
+	for {
+		if e := ctx.Err(); e != nil {
+			return multierr.Combine(err, e)
+		}
+		keys, next, e := client.Scan(cursor, match, int64(c.scanBatch)).Result()
+		if e != nil {
+			return multierr.Combine(err, e)
+		}
+		if len(keys) > 0 {
+			err = multierr.Combine(err, fn(keys))
+		}
+		if next == 0 {
+			return err
+		}
+		cursor = next
+	}
+}
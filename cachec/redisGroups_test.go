@@ -0,0 +1,77 @@
+package cachec_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	redis "github.com/Seann-Moser/ociredis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/cachec/cachectest"
+)
+
+func TestRedisGroupKeys(t *testing.T) {
+	ctx := context.Background()
+	s := cachectest.StartRedis(t)
+	c := cachec.NewRedisCache(redis.NewClient(&redis.Options{Addr: s.Addr(), Context: ctx}), time.Minute, "groups", true)
+	c.SetGroupKeys(true)
+	c.SetScanBatch(2)
+	client := cachec.NewClient(cachec.WithCache(c))
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, client.Set(ctx, "users", fmt.Sprint(i), i))
+	}
+	assert.NoError(t, client.Set(ctx, "orders", "1", 1))
+	assert.NoError(t, client.Set(ctx, "users:archived", "1", 1))
+	assert.NoError(t, client.Set(ctx, "users}:x", "1", 1))
+	tenantCtx := cachec.ContextWithTenant(ctx, "a")
+	assert.NoError(t, client.Set(tenantCtx, "users", "1", 1))
+
+	keys, err := c.GroupKeys(ctx, "users")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 5)
+	for _, k := range keys {
+		assert.True(t, strings.HasPrefix(k, "{users}:"))
+	}
+	keys, err = c.GroupKeys(ctx, cachec.TenantGroup(tenantCtx, "users"))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	var out int
+	assert.NoError(t, client.Get(ctx, "orders", "1", &out))
+	assert.Equal(t, 1, out)
+
+	deleted, err := c.DeleteGroup(ctx, "users")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, deleted)
+	keys, err = c.GroupKeys(ctx, "users")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	for _, group := range []string{"orders", "users:archived", "users}:x"} {
+		keys, err = c.GroupKeys(ctx, group)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1, "groups sharing a prefix with a deleted group are kept: %s", group)
+	}
+}
+
+func TestRedisDeleteCacheWithoutKeyRecord(t *testing.T) {
+	ctx := context.Background()
+	s := cachectest.StartRedis(t)
+	c := cachec.NewRedisCache(redis.NewClient(&redis.Options{Addr: s.Addr(), Context: ctx}), time.Minute, "groups", true)
+	c.SetGroupKeys(true)
+	ctx = cachec.ContextWithCache(ctx, c)
+
+	assert.NoError(t, c.SetCache(ctx, "reports", cachec.GroupKeyPrefix("reports")+"1", "value"))
+	assert.NoError(t, c.SetCache(ctx, "reports", cachec.GroupKeyPrefix("reports")+"2", "value"))
+	assert.NoError(t, cachec.NewMonitor().DeleteCache(ctx, "reports"))
+	keys, err := c.GroupKeys(ctx, "reports")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	c.SetGroupKeys(false)
+	assert.Error(t, cachec.NewMonitor().DeleteCache(ctx, "reports"))
+}
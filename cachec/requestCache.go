@@ -0,0 +1,169 @@
+package cachec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var _ Cache = &RequestCache{}
+
+// RequestCache memoizes cache reads for the lifetime of a single request in front of a parent cache.
+// It is safe to use from goroutines spawned by the request, concurrent misses of a key share one parent read.
+type RequestCache struct {
+	parent Cache
+	mutex  *sync.RWMutex
+	items  map[string]requestCacheItem
+	flight *flightGroup
+}
+
+type requestCacheItem struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewRequestCache(parent Cache) *RequestCache {
+	return &RequestCache{
+		parent: parent,
+		mutex:  &sync.RWMutex{},
+		items:  map[string]requestCacheItem{},
+		flight: newFlightGroup(),
+	}
+}
+
+// RequestCacheMiddleware attaches a RequestCache wrapping the context cache to every request.
+func RequestCacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := NewRequestCache(GetCacheFromContext(r.Context()))
+		defer rc.Close()
+		next.ServeHTTP(w, r.WithContext(ContextWithCache(r.Context(), rc)))
+	})
+}
+
+func (c *RequestCache) GetName() string {
+	if c.parent == nil {
+		return "REQUESTCACHE"
+	}
+	return fmt.Sprintf("REQUESTCACHE_%s", c.parent.GetName())
+}
+
+// GetParentCaches is empty so group update checks are answered from the request scope.
+func (c *RequestCache) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+func (c *RequestCache) Ping(ctx context.Context) error {
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.Ping(ctx)
+}
+
+// Close discards the memoized entries, the parent cache is left open.
+func (c *RequestCache) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items = map[string]requestCacheItem{}
+}
+
+func (c *RequestCache) DeleteKey(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	delete(c.items, key)
+	c.mutex.Unlock()
+	if c.parent == nil {
+		return nil
+	}
+	return c.parent.DeleteKey(ctx, key)
+}
+
+func (c *RequestCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	if c.parent != nil {
+		if err := c.parent.SetCache(ctx, group, key, item); err != nil {
+			return err
+		}
+	}
+	return c.set(key, 0, item)
+}
+
+func (c *RequestCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	if c.parent != nil {
+		if err := c.parent.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); err != nil {
+			return err
+		}
+	}
+	return c.set(key, cacheTimeout, item)
+}
+
+func (c *RequestCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	if data, found := c.get(key); found {
+		return data, nil
+	}
+	if c.parent == nil {
+		return nil, ErrCacheMiss
+	}
+	data, err := c.flight.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		// a load that finished between the check above and joining the flight has stored the item.
+		if data, found := c.get(key); found {
+			return data, nil
+		}
+		data, err := c.parent.GetCache(ctx, group, key)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.set(key, 0, data)
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
+
+func (c *RequestCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if c.parent == nil {
+		return 0, ErrNotSupported
+	}
+	return c.parent.TTL(ctx, key)
+}
+
+// Touch extends the parent entry, the memoized copy lives until the request ends.
+func (c *RequestCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if c.parent == nil {
+		return ErrNotSupported
+	}
+	return c.parent.Touch(ctx, key, ttl)
+}
+
+func (c *RequestCache) Persist(ctx context.Context, key string) error {
+	if c.parent == nil {
+		return ErrNotSupported
+	}
+	return c.parent.Persist(ctx, key)
+}
+
+func (c *RequestCache) get(key string) ([]byte, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	it, found := c.items[key]
+	if !found || (!it.expires.IsZero() && !time.Now().Before(it.expires)) {
+		return nil, false
+	}
+	return it.data, true
+}
+
+func (c *RequestCache) set(key string, cacheTimeout time.Duration, item interface{}) error {
+	data, err := encodeItem(item)
+	if err != nil {
+		return err
+	}
+	it := requestCacheItem{data: data}
+	if cacheTimeout > 0 {
+		it.expires = time.Now().Add(cacheTimeout)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items[key] = it
+	return nil
+}
@@ -0,0 +1,62 @@
+package cachec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingCache struct {
+	Cache
+	mutex *sync.Mutex
+	gets  int
+}
+
+func (c *countingCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	c.mutex.Lock()
+	c.gets++
+	c.mutex.Unlock()
+	return c.Cache.GetCache(ctx, group, key)
+}
+
+func TestRequestCache(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	parent := &countingCache{Cache: NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), mutex: &sync.Mutex{}}
+	ctx := ContextWithCache(context.Background(), parent)
+	assert.NoError(t, SetWithExpiration[string](ctx, time.Minute, "", "request", "value"))
+	parent.gets = 0
+
+	handler := RequestCacheMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := Get[string](r.Context(), "", "request")
+				assert.NoError(t, err)
+				assert.Equal(t, "value", *v)
+			}()
+		}
+		wg.Wait()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	assert.Equal(t, 1, parent.gets, "concurrent reads of the request share one parent read")
+
+	rc := NewRequestCache(parent)
+	parent.gets = 0
+	for i := 0; i < 5; i++ {
+		_, err := rc.GetCache(ctx, "", GetKey[string]("", "request"))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, parent.gets)
+
+	assert.NoError(t, rc.DeleteKey(ctx, GetKey[string]("", "request")))
+	_, err := rc.GetCache(ctx, "", GetKey[string]("", "request"))
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
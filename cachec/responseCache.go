@@ -0,0 +1,398 @@
+package cachec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seann-Moser/cutil/logc"
+	"go.uber.org/zap"
+)
+
+const (
+	ResponseCacheGroup  = "cachec_responses"
+	ResponseCacheHeader = "X-Cache"
+
+	DefaultMaxResponseSize = 1 << 20
+)
+
+// ResponseCache caches whole GET/HEAD responses, entries are stale once one of their groups is updated.
+// Requests with an Authorization or Cookie header only share responses marked public or s-maxage, unless the
+// credentials are part of the key (RFC 9111 section 3.5).
+type ResponseCache struct {
+	client        *Client
+	ttl           time.Duration
+	groups        []string
+	queryParams   []string
+	varyHeaders   []string
+	maxSize       int
+	perCredential bool
+}
+
+type ResponseCacheOption func(rc *ResponseCache)
+
+func WithResponseTTL(ttl time.Duration) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.ttl = ttl
+	}
+}
+
+// WithResponseGroups sets the groups the responses depend on, usually table names.
+func WithResponseGroups(groups ...string) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.groups = append(rc.groups, groups...)
+	}
+}
+
+// WithQueryParams limits the query params used in the key, by default every param is used.
+func WithQueryParams(params ...string) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.queryParams = append(rc.queryParams, params...)
+	}
+}
+
+func WithVaryHeaders(headers ...string) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		for _, h := range headers {
+			rc.varyHeaders = append(rc.varyHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithCredentialKey caches the responses of requests with credentials per Authorization and Cookie header.
+func WithCredentialKey() ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.perCredential = true
+	}
+}
+
+func WithResponseClient(client *Client) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.client = client
+	}
+}
+
+// WithMaxResponseSize skips caching bodies larger than size bytes.
+func WithMaxResponseSize(size int) ResponseCacheOption {
+	return func(rc *ResponseCache) {
+		rc.maxSize = size
+	}
+}
+
+func NewResponseCache(opts ...ResponseCacheOption) *ResponseCache {
+	rc := &ResponseCache{
+		ttl:     5 * time.Minute,
+		maxSize: DefaultMaxResponseSize,
+	}
+	for _, opt := range opts {
+		opt(rc)
+	}
+	return rc
+}
+
+// ResponseCacheMiddleware is shorthand for NewResponseCache(opts...).Middleware.
+func ResponseCacheMiddleware(opts ...ResponseCacheOption) func(http.Handler) http.Handler {
+	return NewResponseCache(opts...).Middleware
+}
+
+type cachedResponse struct {
+	Status  int              `json:"status"`
+	Header  http.Header      `json:"header"`
+	Body    []byte           `json:"body"`
+	Groups  map[string]int64 `json:"groups"`
+	Created time.Time        `json:"created"`
+	// Public responses may be served to requests with credentials.
+	Public bool `json:"public,omitempty"`
+	// Vary lists the request headers the response varies on, the entry only points to the variants stored under
+	// keys including their values.
+	Vary []string `json:"vary,omitempty"`
+}
+
+func (rc *ResponseCache) getClient() *Client {
+	if rc.client != nil {
+		return rc.client
+	}
+	return Default()
+}
+
+func (rc *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		directives := parseCacheControl(r.Header.Get("Cache-Control"))
+		if _, found := directives["no-store"]; found {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c := rc.getClient()
+		ctx := c.context(r.Context())
+		key, err := rc.Key(r)
+		if err != nil {
+			logc.Debug(ctx, "skipping response cache", zap.String("path", r.URL.Path), zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		// without the versions of every group a stored response can not be checked, nor stored for later checks.
+		groups, err := rc.groupVersions(ctx, c)
+		if err != nil {
+			logc.Debug(ctx, "skipping response cache", zap.String("path", r.URL.Path), zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		// without the credentials in the key only responses meant for everyone can be shared.
+		publicOnly := hasCredentials(r) && !rc.perCredential
+
+		if _, found := directives["no-cache"]; !found {
+			if resp, ok := rc.lookup(ctx, c, r, key, groups); ok && (resp.Public || !publicOnly) {
+				resp.write(w, r)
+				return
+			}
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK, maxSize: rc.maxSize}
+		next.ServeHTTP(rec, r)
+		if !rec.wroteHeader {
+			rec.header = w.Header().Clone()
+		}
+		ttl, public, ok := rc.storable(rec)
+		if !ok || (publicOnly && !public) {
+			return
+		}
+		resp := cachedResponse{
+			Status:  rec.status,
+			Header:  rec.header,
+			Body:    rec.body.Bytes(),
+			Groups:  groups,
+			Created: time.Now(),
+			Public:  public,
+		}
+		if err := rc.store(ctx, c, r, key, ttl, resp); err != nil {
+			logc.Debug(ctx, "failed caching response", zap.String("path", r.URL.Path), zap.Error(err))
+		}
+	})
+}
+
+// store writes the response, responses with a Vary header are stored under the variant key of the request and
+// the key records the headers to build it from.
+func (rc *ResponseCache) store(ctx context.Context, c *Client, r *http.Request, key string, ttl time.Duration, resp cachedResponse) error {
+	if vary := varyHeaders(resp.Header); len(vary) > 0 {
+		index := cachedResponse{Groups: resp.Groups, Created: resp.Created, Vary: vary}
+		if err := rc.write(ctx, c, key, ttl, index); err != nil {
+			return err
+		}
+		key = variantKey(c, r, key, vary)
+	}
+	return rc.write(ctx, c, key, ttl, resp)
+}
+
+func (rc *ResponseCache) write(ctx context.Context, c *Client, key string, ttl time.Duration, resp cachedResponse) error {
+	data, err := c.encode(resp)
+	if err != nil {
+		return err
+	}
+	return c.Cache(ctx).SetCacheWithExpiration(ctx, ttl, ResponseCacheGroup, key, data)
+}
+
+// lookup loads the response for the request, following the Vary header of the stored response.
+func (rc *ResponseCache) lookup(ctx context.Context, c *Client, r *http.Request, key string, groups map[string]int64) (*cachedResponse, bool) {
+	resp, ok := rc.load(ctx, c, key, groups)
+	if !ok || len(resp.Vary) == 0 {
+		return resp, ok
+	}
+	return rc.load(ctx, c, variantKey(c, r, key, resp.Vary), groups)
+}
+
+// Key builds the cache key from the method, path, selected query params and vary headers, and a hash of the
+// credentials with WithCredentialKey.
+func (rc *ResponseCache) Key(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if len(rc.queryParams) > 0 {
+		selected := url.Values{}
+		for _, p := range rc.queryParams {
+			if v, found := query[p]; found {
+				selected[p] = v
+			}
+		}
+		query = selected
+	}
+	parts := []string{r.Method, r.URL.Path, query.Encode()}
+	for _, h := range rc.varyHeaders {
+		parts = append(parts, h+"="+strings.Join(r.Header.Values(h), ","))
+	}
+	if rc.perCredential && hasCredentials(r) {
+		sum := sha256.New()
+		for _, h := range []string{"Authorization", "Cookie"} {
+			sum.Write([]byte(h + "=" + strings.Join(r.Header.Values(h), ",") + "\n"))
+		}
+		parts = append(parts, "credentials="+hex.EncodeToString(sum.Sum(nil)))
+	}
+	return rc.getClient().KeyCtx(r.Context(), "response", parts...)
+}
+
+// groupVersions returns the last update of every group the responses depend on.
+func (rc *ResponseCache) groupVersions(ctx context.Context, c *Client) (map[string]int64, error) {
+	groups := make(map[string]int64, len(rc.groups))
+	for _, g := range rc.groups {
+		updated, err := c.Monitor().LastUpdated(ctx, g)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", g, err)
+		}
+		groups[g] = updated.UnixNano()
+	}
+	return groups, nil
+}
+
+func (rc *ResponseCache) load(ctx context.Context, c *Client, key string, groups map[string]int64) (*cachedResponse, bool) {
+	data, err := c.Cache(ctx).GetCache(ctx, ResponseCacheGroup, key)
+	if err != nil {
+		return nil, false
+	}
+	var resp cachedResponse
+	if err := c.decode(data, &resp); err != nil {
+		return nil, false
+	}
+	for g, updated := range groups {
+		if resp.Groups[g] != updated {
+			return nil, false
+		}
+	}
+	return &resp, true
+}
+
+// storable returns how long the response may be cached and whether it is public, explicitly marked public or
+// with s-maxage.
+func (rc *ResponseCache) storable(rec *responseRecorder) (time.Duration, bool, bool) {
+	if rec.overflow || !cacheableStatus(rec.status) || rec.header.Get("Set-Cookie") != "" {
+		return 0, false, false
+	}
+	for _, h := range varyHeaders(rec.header) {
+		if h == "*" {
+			return 0, false, false
+		}
+	}
+	directives := parseCacheControl(rec.header.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, found := directives[d]; found {
+			return 0, false, false
+		}
+	}
+	_, public := directives["public"]
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, found := directives[d]; found {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				return 0, false, false
+			}
+			return time.Duration(seconds) * time.Second, public || d == "s-maxage", true
+		}
+	}
+	return rc.ttl, public, rc.ttl > 0
+}
+
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+// varyHeaders returns the canonical names listed by the Vary header of a response.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// variantKey extends key with the values of the request headers a response varies on.
+func variantKey(c *Client, r *http.Request, key string, vary []string) string {
+	parts := []string{key}
+	for _, h := range vary {
+		parts = append(parts, h+"="+strings.Join(r.Header.Values(h), ","))
+	}
+	return c.Key("response", parts...)
+}
+
+func (resp *cachedResponse) write(w http.ResponseWriter, r *http.Request) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ResponseCacheHeader, "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(resp.Created).Seconds())))
+	w.WriteHeader(resp.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	maxSize     int
+	overflow    bool
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.Header().Set(ResponseCacheHeader, "MISS")
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.overflow {
+		if rec.body.Len()+len(b) > rec.maxSize {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// cacheableStatus reports the status codes that are cacheable by default (RFC 9110 section 15.1).
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+		http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone, http.StatusRequestURITooLong,
+		http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+func parseCacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
@@ -0,0 +1,165 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	calls := 0
+	handler := ResponseCacheMiddleware(
+		WithResponseClient(client),
+		WithResponseGroups("users"),
+		WithQueryParams("page"),
+		WithVaryHeaders("accept-language"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("private") != "" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+
+	serve := func(target string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/users?page=1")
+	assert.Equal(t, "MISS", w.Header().Get(ResponseCacheHeader))
+	assert.Equal(t, `{"call":1}`, w.Body.String())
+
+	w = serve("/users?page=1&ignored=true")
+	assert.Equal(t, "HIT", w.Header().Get(ResponseCacheHeader))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"call":1}`, w.Body.String())
+
+	assert.Equal(t, `{"call":2}`, serve("/users?page=2").Body.String())
+	assert.Equal(t, `{"call":3}`, serve("/users?page=1", "Accept-Language", "fr").Body.String())
+	assert.Equal(t, `{"call":4}`, serve("/users?page=1", "Cache-Control", "no-cache").Body.String())
+	assert.Equal(t, `{"call":4}`, serve("/users?page=1").Body.String())
+
+	assert.NoError(t, client.Monitor().UpdateCache(client.context(context.Background()), "users", "1"))
+	assert.Equal(t, `{"call":5}`, serve("/users?page=1").Body.String())
+	assert.Equal(t, `{"call":5}`, serve("/users?page=1").Body.String())
+	assert.NoError(t, client.Monitor().UpdateCache(client.context(context.Background()), "users", "2"))
+	assert.Equal(t, `{"call":6}`, serve("/users?page=1").Body.String(), "updates within the same second invalidate")
+
+	assert.Equal(t, `{"call":7}`, serve("/private?page=1&private=1").Body.String())
+	assert.Equal(t, `{"call":8}`, serve("/private?page=1&private=1").Body.String())
+}
+
+func TestResponseCacheGroupFailure(t *testing.T) {
+	failing := false
+	failer := func(ctx context.Context, op *Operation, next Invoker) error {
+		if failing && op.Group == GroupPrefix {
+			return errors.New("unavailable")
+		}
+		return next(ctx, op)
+	}
+	client := NewClient(WithCache(WrapCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), failer)))
+	calls := 0
+	handler := ResponseCacheMiddleware(WithResponseClient(client), WithResponseGroups("users"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+	serve := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, `{"call":1}`, serve())
+	assert.Equal(t, `{"call":1}`, serve())
+	assert.NoError(t, client.Monitor().UpdateCache(client.context(context.Background()), "users", "1"))
+	failing = true
+	assert.Equal(t, `{"call":2}`, serve(), "responses are not served when the group versions can not be read")
+	failing = false
+	assert.Equal(t, `{"call":3}`, serve())
+	assert.Equal(t, `{"call":3}`, serve())
+}
+
+func TestResponseCacheCredentials(t *testing.T) {
+	newHandler := func(opts ...ResponseCacheOption) http.Handler {
+		client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+		return ResponseCacheMiddleware(append(opts, WithResponseClient(client))...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("public") != "" {
+				w.Header().Set("Cache-Control", "public, max-age=60")
+			}
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+	}
+	serve := func(handler http.Handler, target, auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	handler := newHandler()
+	assert.Equal(t, "Bearer alice", serve(handler, "/me", "Bearer alice").Body.String())
+	assert.Equal(t, "Bearer bob", serve(handler, "/me", "Bearer bob").Body.String(), "credentials do not share entries")
+	assert.Equal(t, "MISS", serve(handler, "/me", "Bearer alice").Header().Get(ResponseCacheHeader))
+	assert.Equal(t, "", serve(handler, "/me", "").Body.String())
+	assert.Equal(t, "MISS", serve(handler, "/me", "Bearer alice").Header().Get(ResponseCacheHeader), "anonymous responses are not served to requests with credentials")
+
+	assert.Equal(t, "Bearer alice", serve(handler, "/me?public=1", "Bearer alice").Body.String())
+	w := serve(handler, "/me?public=1", "Bearer bob")
+	assert.Equal(t, "HIT", w.Header().Get(ResponseCacheHeader))
+	assert.Equal(t, "Bearer alice", w.Body.String(), "public responses are shared")
+
+	handler = newHandler(WithCredentialKey())
+	assert.Equal(t, "Bearer alice", serve(handler, "/me", "Bearer alice").Body.String())
+	assert.Equal(t, "Bearer bob", serve(handler, "/me", "Bearer bob").Body.String())
+	w = serve(handler, "/me", "Bearer alice")
+	assert.Equal(t, "HIT", w.Header().Get(ResponseCacheHeader))
+	assert.Equal(t, "Bearer alice", w.Body.String())
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	client := NewClient(WithCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	handler := ResponseCacheMiddleware(WithResponseClient(client))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Encoding, x-tenant")
+		}
+		_, _ = w.Write([]byte(r.Header.Get("X-Tenant")))
+	}))
+	serve := func(target, tenant string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("X-Tenant", tenant)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, "a", serve("/config", "a").Body.String())
+	assert.Equal(t, "b", serve("/config", "b").Body.String(), "the response varies on the tenant header")
+	for _, tenant := range []string{"a", "b"} {
+		w := serve("/config", tenant)
+		assert.Equal(t, "HIT", w.Header().Get(ResponseCacheHeader))
+		assert.Equal(t, tenant, w.Body.String())
+	}
+
+	serve("/any", "a")
+	assert.Equal(t, "MISS", serve("/any", "a").Header().Get(ResponseCacheHeader))
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Seann-Moser/cutil/logc"
+	"go.uber.org/zap"
+)
+
+type requestCtxName string
+
+const requestCtx = requestCtxName("session-request")
+
+var ErrNoMiddleware = errors.New("session middleware is not installed")
+
+// request is the session state of a request handled by the middleware.
+type request[T any] struct {
+	store   *Store[T]
+	w       http.ResponseWriter
+	session *Session[T]
+	// loaded is the encoded session as read, the session is only written back when it changed.
+	loaded []byte
+}
+
+// Middleware loads the session of the request cookie into the request context and slides its expiration.
+// Changes to the session data are saved once the handler returns, unless the session has been deleted meanwhile.
+// Start and End replace or remove the session.
+func (s *Store[T]) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			req := &request[T]{store: s, w: w}
+			if cookie, err := r.Cookie(s.cookie.Name); err == nil {
+				sess, data, err := s.load(ctx, cookie.Value)
+				if err == nil {
+					err = s.Touch(ctx, sess)
+				}
+				switch {
+				case err == nil:
+					req.session, req.loaded = sess, data
+				case errors.Is(err, ErrNotFound):
+					s.clearCookie(w)
+				default:
+					logc.Error(ctx, "failed loading session", zap.Error(err))
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, requestCtx, req)))
+			if req.session == nil {
+				return
+			}
+			data, err := s.codec.Marshal(req.session)
+			if err != nil || bytes.Equal(data, req.loaded) {
+				return
+			}
+			// sessions ended by a concurrent request are not written back.
+			err = s.Touch(ctx, req.session)
+			if err == nil {
+				err = s.Save(ctx, req.session)
+			}
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				logc.Error(ctx, "failed saving session", zap.Error(err))
+			}
+		})
+	}
+}
+
+// FromContext returns the session of the request, false when the request has none.
+func (s *Store[T]) FromContext(ctx context.Context) (*Session[T], bool) {
+	req, ok := s.request(ctx)
+	if !ok || req.session == nil {
+		return nil, false
+	}
+	return req.session, true
+}
+
+// Start creates a session for the user and sets its cookie, call it before writing the response body.
+// The current session is deleted so an id set before login cannot be reused.
+func (s *Store[T]) Start(ctx context.Context, userID string, data T) (*Session[T], error) {
+	req, ok := s.request(ctx)
+	if !ok {
+		return nil, ErrNoMiddleware
+	}
+	if req.session != nil {
+		if err := s.Delete(ctx, req.session.ID); err != nil {
+			return nil, err
+		}
+	}
+	sess, err := s.Create(ctx, userID, data)
+	if err != nil {
+		return nil, err
+	}
+	req.session = sess
+	req.loaded, _ = s.codec.Marshal(sess)
+	cookie := s.cookie
+	cookie.Value = sess.ID
+	http.SetCookie(req.w, &cookie)
+	return sess, nil
+}
+
+// End deletes the session of the request and expires its cookie.
+func (s *Store[T]) End(ctx context.Context) error {
+	req, ok := s.request(ctx)
+	if !ok {
+		return ErrNoMiddleware
+	}
+	if req.session == nil {
+		return nil
+	}
+	id := req.session.ID
+	req.session = nil
+	s.clearCookie(req.w)
+	return s.Delete(ctx, id)
+}
+
+func (s *Store[T]) request(ctx context.Context) (*request[T], bool) {
+	req, ok := ctx.Value(requestCtx).(*request[T])
+	if !ok || req.store != s {
+		return nil, false
+	}
+	return req, true
+}
+
+func (s *Store[T]) clearCookie(w http.ResponseWriter) {
+	cookie := s.cookie
+	cookie.Value = ""
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(w, &cookie)
+}
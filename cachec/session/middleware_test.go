@@ -0,0 +1,81 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore[account]()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		_, err := store.Start(r.Context(), "u1", account{Name: "ada"})
+		assert.NoError(t, err)
+	})
+	mux.HandleFunc("/promote", func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := store.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sess.Data.Roles = append(sess.Data.Roles, "admin")
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := store.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(sess.Data.Name + ":" + sess.Data.Roles[0]))
+	})
+	mux.HandleFunc("/concurrent-logout", func(w http.ResponseWriter, r *http.Request) {
+		sess, _ := store.FromContext(r.Context())
+		sess.Data.Roles = append(sess.Data.Roles, "editor")
+		// another request of the session logs out while this one runs.
+		assert.NoError(t, store.Delete(r.Context(), sess.ID))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, store.End(r.Context()))
+	})
+	handler := store.Middleware()(mux)
+	serve := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/promote").Code)
+	login := serve("/login").Result().Cookies()
+	assert.Len(t, login, 1)
+	assert.Equal(t, DefaultCookieName, login[0].Name)
+	assert.True(t, login[0].HttpOnly)
+	assert.True(t, login[0].Secure)
+
+	assert.Equal(t, http.StatusOK, serve("/promote", login[0]).Code)
+	me := serve("/me", login[0])
+	assert.Equal(t, http.StatusOK, me.Code)
+	assert.Equal(t, "ada:admin", me.Body.String(), "changes made by handlers are saved")
+
+	relogin := serve("/login", login[0]).Result().Cookies()
+	assert.Len(t, relogin, 1)
+	assert.NotEqual(t, login[0].Value, relogin[0].Value)
+	stale := serve("/me", login[0])
+	assert.Equal(t, http.StatusUnauthorized, stale.Code, "login replaces the previous session")
+	assert.Equal(t, -1, stale.Result().Cookies()[0].MaxAge)
+
+	assert.Equal(t, http.StatusOK, serve("/concurrent-logout", relogin[0]).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("/me", relogin[0]).Code, "ended sessions are not saved again")
+
+	relogin = serve("/login").Result().Cookies()
+	logout := serve("/logout", relogin[0]).Result().Cookies()
+	assert.Len(t, logout, 1)
+	assert.Equal(t, -1, logout[0].MaxAge)
+	assert.Equal(t, http.StatusUnauthorized, serve("/me", relogin[0]).Code)
+}
@@ -0,0 +1,268 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	DefaultCookieName  = "session"
+	DefaultTTL         = 30 * time.Minute
+	DefaultMaxLifetime = 7 * 24 * time.Hour
+
+	// Group is the cache group of the sessions, keys start with it so caches using the group key layout can drop
+	// every session with DeleteGroup.
+	Group = "sessions"
+	// UserGroup is the cache group of the revocation tags of the users. Sessions of users without a tag are treated
+	// as revoked, so the group must not be limited by a quota that evicts entries.
+	UserGroup = "session_users"
+
+	idBytes = 32
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// NewID returns a session id with 256 bits from crypto/rand, encoded as unpadded base64url.
+func NewID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Session is stored under a hash of its id, so the ids of live sessions cannot be read back from the cache.
+type Session[T any] struct {
+	ID       string    `json:"-"`
+	UserID   string    `json:"user_id,omitempty"`
+	IssuedAt time.Time `json:"issued_at"`
+	Data     T         `json:"data"`
+}
+
+// Store keeps sessions with data of type T in a cache. Sessions expire after being idle for the ttl and at the
+// latest after the maximum lifetime. Every user with a session has a revocation tag that is kept for the maximum
+// lifetime after their last session was created.
+type Store[T any] struct {
+	cache       cachec.Cache
+	codec       cachec.Codec
+	ttl         time.Duration
+	maxLifetime time.Duration
+	cookie      http.Cookie
+}
+
+type Option[T any] func(s *Store[T])
+
+// WithTTL sets the idle timeout, every load slides the expiration by ttl.
+func WithTTL[T any](ttl time.Duration) Option[T] {
+	return func(s *Store[T]) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithMaxLifetime sets how long a session lives at most, however often it is used.
+func WithMaxLifetime[T any](lifetime time.Duration) Option[T] {
+	return func(s *Store[T]) {
+		if lifetime > 0 {
+			s.maxLifetime = lifetime
+		}
+	}
+}
+
+// WithCodec encodes the sessions with codec instead of json.
+func WithCodec[T any](codec cachec.Codec) Option[T] {
+	return func(s *Store[T]) {
+		s.codec = codec
+	}
+}
+
+// WithCookie sets the name and attributes of the session cookie, its value and expiration are managed by the store.
+func WithCookie[T any](cookie http.Cookie) Option[T] {
+	return func(s *Store[T]) {
+		s.cookie = cookie
+	}
+}
+
+func NewStore[T any](c cachec.Cache, opts ...Option[T]) *Store[T] {
+	s := &Store[T]{
+		cache:       c,
+		codec:       cachec.JSONCodec{},
+		ttl:         DefaultTTL,
+		maxLifetime: DefaultMaxLifetime,
+		cookie: http.Cookie{
+			Name:     DefaultCookieName,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewMemoryStore keeps the sessions in a GoCache, for tests and single process services.
+func NewMemoryStore[T any](opts ...Option[T]) *Store[T] {
+	return NewStore[T](cachec.NewGoCache(cache.New(DefaultTTL, time.Minute), DefaultTTL, Group), opts...)
+}
+
+// Create starts a session for the user with a new id, userID may be empty for anonymous sessions.
+func (s *Store[T]) Create(ctx context.Context, userID string, data T) (*Session[T], error) {
+	id, err := NewID()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tagUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	sess := &Session[T]{ID: id, UserID: userID, IssuedAt: time.Now(), Data: data}
+	return sess, s.Save(ctx, sess)
+}
+
+// tagUser makes sure the revocation tag of the user outlives the sessions created now. A missing tag is written as
+// never revoked, the sessions it covered were ended when it went missing.
+func (s *Store[T]) tagUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return nil
+	}
+	key := userTagKey(userID)
+	err := s.cache.Touch(ctx, key, s.maxLifetime)
+	if !errors.Is(err, cachec.ErrCacheMiss) {
+		return err
+	}
+	data, err := s.codec.Marshal(int64(0))
+	if err != nil {
+		return err
+	}
+	return s.cache.SetCacheWithExpiration(ctx, s.maxLifetime, UserGroup, key, data)
+}
+
+// Save writes the session and resets its idle timeout.
+func (s *Store[T]) Save(ctx context.Context, sess *Session[T]) error {
+	ttl, live := s.remaining(sess, time.Now())
+	if !live {
+		return ErrNotFound
+	}
+	data, err := s.codec.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return s.cache.SetCacheWithExpiration(ctx, ttl, Group, sessionKey(sess.ID), data)
+}
+
+// Load returns the session and slides its expiration, expired and revoked sessions return ErrNotFound.
+func (s *Store[T]) Load(ctx context.Context, id string) (*Session[T], error) {
+	sess, _, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sess, s.Touch(ctx, sess)
+}
+
+// Touch extends the session by the idle timeout, never past its maximum lifetime.
+func (s *Store[T]) Touch(ctx context.Context, sess *Session[T]) error {
+	ttl, live := s.remaining(sess, time.Now())
+	if !live {
+		return ErrNotFound
+	}
+	err := s.cache.Touch(ctx, sessionKey(sess.ID), ttl)
+	if errors.Is(err, cachec.ErrCacheMiss) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Store[T]) Delete(ctx context.Context, id string) error {
+	return s.cache.DeleteKey(ctx, sessionKey(id))
+}
+
+// RevokeUser ends every session issued to the user so far, in every process sharing the cache.
+// Instead of finding the sessions it tags the user with the revocation time, which loads compare with the issue
+// time of the session. The tag expires with the maximum lifetime, once no session it covers can be alive.
+func (s *Store[T]) RevokeUser(ctx context.Context, userID string) error {
+	data, err := s.codec.Marshal(time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	return s.cache.SetCacheWithExpiration(ctx, s.maxLifetime, UserGroup, userTagKey(userID), data)
+}
+
+// load returns the session with its encoded form, expired and revoked sessions are deleted.
+func (s *Store[T]) load(ctx context.Context, id string) (*Session[T], []byte, error) {
+	if id == "" {
+		return nil, nil, ErrNotFound
+	}
+	data, err := s.cache.GetCache(ctx, Group, sessionKey(id))
+	if errors.Is(err, cachec.ErrCacheMiss) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var sess Session[T]
+	if err := s.codec.Unmarshal(data, &sess); err != nil {
+		return nil, nil, err
+	}
+	sess.ID = id
+	revoked, err := s.revoked(ctx, &sess)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, live := s.remaining(&sess, time.Now()); !live || revoked {
+		_ = s.Delete(ctx, id)
+		return nil, nil, ErrNotFound
+	}
+	return &sess, data, nil
+}
+
+// revoked fails closed, a session whose user tag is missing may have been revoked before the tag was evicted.
+func (s *Store[T]) revoked(ctx context.Context, sess *Session[T]) (bool, error) {
+	if sess.UserID == "" {
+		return false, nil
+	}
+	data, err := s.cache.GetCache(ctx, UserGroup, userTagKey(sess.UserID))
+	if errors.Is(err, cachec.ErrCacheMiss) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var revokedAt int64
+	if err := s.codec.Unmarshal(data, &revokedAt); err != nil {
+		return false, err
+	}
+	return sess.IssuedAt.UnixNano() <= revokedAt, nil
+}
+
+// remaining returns the expiration for a write at now, capped by the maximum lifetime.
+func (s *Store[T]) remaining(sess *Session[T], now time.Time) (time.Duration, bool) {
+	left := sess.IssuedAt.Add(s.maxLifetime).Sub(now)
+	if left <= 0 {
+		return 0, false
+	}
+	if left < s.ttl {
+		return left, true
+	}
+	return s.ttl, true
+}
+
+func sessionKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return cachec.GroupKeyPrefix(Group) + hex.EncodeToString(sum[:])
+}
+
+func userTagKey(userID string) string {
+	return cachec.GroupKeyPrefix(UserGroup) + userID
+}
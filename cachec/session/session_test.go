@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type account struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func TestNewID(t *testing.T) {
+	seen := map[string]struct{}{}
+	for i := 0; i < 100; i++ {
+		id, err := NewID()
+		assert.NoError(t, err)
+		assert.Len(t, id, 43)
+		assert.NotContains(t, id, "+")
+		assert.NotContains(t, id, "/")
+		seen[id] = struct{}{}
+	}
+	assert.Len(t, seen, 100)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	c := cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	store := NewStore[account](c, WithTTL[account](time.Hour))
+
+	sess, err := store.Create(ctx, "u1", account{Name: "ada", Roles: []string{"admin"}})
+	assert.NoError(t, err)
+	_, err = c.GetCache(ctx, Group, cachec.GroupKeyPrefix(Group)+sess.ID)
+	assert.ErrorIs(t, err, cachec.ErrCacheMiss, "sessions are not stored under their id")
+
+	loaded, err := store.Load(ctx, sess.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, sess.ID, loaded.ID)
+	assert.Equal(t, "u1", loaded.UserID)
+	assert.Equal(t, []string{"admin"}, loaded.Data.Roles)
+
+	_, err = store.Load(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, sess.ID))
+	_, err = store.Load(ctx, sess.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	store := NewStore[account](c, WithTTL[account](time.Hour), WithMaxLifetime[account](2*time.Hour))
+
+	sess, err := store.Create(ctx, "u1", account{})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Touch(ctx, sessionKey(sess.ID), time.Second))
+	_, err = store.Load(ctx, sess.ID)
+	assert.NoError(t, err)
+	ttl, err := c.TTL(ctx, sessionKey(sess.ID))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second), "loads slide the expiration")
+
+	sess.IssuedAt = time.Now().Add(-110 * time.Minute)
+	assert.NoError(t, store.Save(ctx, sess))
+	ttl, err = c.TTL(ctx, sessionKey(sess.ID))
+	assert.NoError(t, err)
+	assert.InDelta(t, 10*time.Minute, ttl, float64(time.Second), "the maximum lifetime caps the expiration")
+
+	sess.IssuedAt = time.Now().Add(-3 * time.Hour)
+	assert.ErrorIs(t, store.Save(ctx, sess), ErrNotFound)
+}
+
+func TestStore_RevokeUser(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore[account]()
+
+	first, err := store.Create(ctx, "u1", account{})
+	assert.NoError(t, err)
+	second, err := store.Create(ctx, "u1", account{})
+	assert.NoError(t, err)
+	other, err := store.Create(ctx, "u2", account{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.RevokeUser(ctx, "u1"))
+	for _, sess := range []*Session[account]{first, second} {
+		_, err = store.Load(ctx, sess.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	_, err = store.Load(ctx, other.ID)
+	assert.NoError(t, err)
+
+	renewed, err := store.Create(ctx, "u1", account{})
+	assert.NoError(t, err)
+	_, err = store.Load(ctx, renewed.ID)
+	assert.NoError(t, err, "sessions issued after the revocation are valid")
+
+	assert.NoError(t, store.cache.DeleteKey(ctx, userTagKey("u2")))
+	_, err = store.Load(ctx, other.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "sessions of users without a tag are treated as revoked")
+	other, err = store.Create(ctx, "u2", account{})
+	assert.NoError(t, err)
+	_, err = store.Load(ctx, other.ID)
+	assert.NoError(t, err)
+}
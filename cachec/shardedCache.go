@@ -0,0 +1,397 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const DefaultVirtualNodes = 160
+
+var ErrNoShards = errors.New("sharded cache has no nodes")
+
+var _ Cache = &ShardedCache{}
+var _ Cache = &Shard{}
+
+// ShardedCache spreads keys across independent caches with a consistent hash ring.
+// Adding or removing a node only remaps the keys next to its virtual nodes.
+type ShardedCache struct {
+	mutex        *sync.RWMutex
+	nodes        map[string]*Shard
+	ring         []ringPoint
+	virtualNodes int
+	replicas     int
+}
+
+type ringPoint struct {
+	hash uint32
+	node string
+}
+
+type ShardedOption func(s *ShardedCache)
+
+// WithVirtualNodes sets the number of points each node has on the ring.
+func WithVirtualNodes(n int) ShardedOption {
+	return func(s *ShardedCache) {
+		if n > 0 {
+			s.virtualNodes = n
+		}
+	}
+}
+
+// WithReplicas writes every key to the next n nodes on the ring as well, reads fall back to them.
+func WithReplicas(n int) ShardedOption {
+	return func(s *ShardedCache) {
+		s.replicas = n
+	}
+}
+
+func NewShardedCache(nodes map[string]Cache, opts ...ShardedOption) *ShardedCache {
+	s := &ShardedCache{
+		mutex:        &sync.RWMutex{},
+		nodes:        map[string]*Shard{},
+		virtualNodes: DefaultVirtualNodes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for name, c := range nodes {
+		s.addNode(name, c)
+	}
+	s.buildRing()
+	return s
+}
+
+// AddNode adds or replaces a node.
+func (s *ShardedCache) AddNode(name string, c Cache) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.addNode(name, c)
+	s.buildRing()
+}
+
+// RemoveNode takes the node out of the ring and returns its cache, the cache is not closed.
+func (s *ShardedCache) RemoveNode(name string) Cache {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	shard, found := s.nodes[name]
+	if !found {
+		return nil
+	}
+	delete(s.nodes, name)
+	s.buildRing()
+	return shard.Cache
+}
+
+// Node returns the name of the node that owns key, empty when there are no nodes.
+func (s *ShardedCache) Node(key string) string {
+	owners := s.lookup(key)
+	if len(owners) == 0 {
+		return ""
+	}
+	return owners[0].name
+}
+
+func (s *ShardedCache) addNode(name string, c Cache) {
+	shard := &Shard{Cache: c, name: name, ring: s}
+	shard.healthy.Store(true)
+	s.nodes[name] = shard
+}
+
+func (s *ShardedCache) buildRing() {
+	ring := make([]ringPoint, 0, len(s.nodes)*s.virtualNodes)
+	for name := range s.nodes {
+		for i := 0; i < s.virtualNodes; i++ {
+			ring = append(ring, ringPoint{hash: crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(i))), node: name})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash == ring[j].hash {
+			return ring[i].node < ring[j].node
+		}
+		return ring[i].hash < ring[j].hash
+	})
+	s.ring = ring
+}
+
+// owners returns the primary node for key followed by its replicas, healthy shards first.
+func (s *ShardedCache) owners(key string) []*Shard {
+	owners := s.lookup(key)
+	sort.SliceStable(owners, func(i, j int) bool {
+		return owners[i].Healthy() && !owners[j].Healthy()
+	})
+	return owners
+}
+
+// lookup returns the primary node for key followed by its replicas in ring order.
+func (s *ShardedCache) lookup(key string) []*Shard {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if len(s.ring) == 0 {
+		return nil
+	}
+	count := s.replicas + 1
+	if count > len(s.nodes) {
+		count = len(s.nodes)
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(s.ring), func(i int) bool {
+		return s.ring[i].hash >= h
+	})
+	owners := make([]*Shard, 0, count)
+	seen := map[string]struct{}{}
+	for n := 0; n < len(s.ring) && len(owners) < count; n++ {
+		p := s.ring[(i+n)%len(s.ring)]
+		if _, found := seen[p.node]; found {
+			continue
+		}
+		seen[p.node] = struct{}{}
+		owners = append(owners, s.nodes[p.node])
+	}
+	return owners
+}
+
+func (s *ShardedCache) shards() []*Shard {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	shards := make([]*Shard, 0, len(s.nodes))
+	for _, shard := range s.nodes {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shards[i].name < shards[j].name
+	})
+	return shards
+}
+
+func (s *ShardedCache) GetName() string {
+	names := []string{}
+	for _, shard := range s.shards() {
+		names = append(names, shard.name)
+	}
+	return fmt.Sprintf("SHARDEDCACHE_%s", strings.Join(names, "-"))
+}
+
+// GetParentCaches returns every shard by node name, use Shard.Healthy for the shard state.
+func (s *ShardedCache) GetParentCaches() map[string]Cache {
+	data := map[string]Cache{}
+	for _, shard := range s.shards() {
+		data[shard.name] = shard
+	}
+	return data
+}
+
+func (s *ShardedCache) Ping(ctx context.Context) error {
+	var err error
+	for _, shard := range s.shards() {
+		e := shard.Cache.Ping(ctx)
+		shard.observe(e)
+		if e != nil {
+			err = multierr.Combine(err, fmt.Errorf("shard %s: %w", shard.name, e))
+		}
+	}
+	return err
+}
+
+func (s *ShardedCache) Close() {
+	for _, shard := range s.shards() {
+		shard.Cache.Close()
+	}
+}
+
+func (s *ShardedCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return s.each(key, false, func(c Cache) error {
+		return c.SetCache(ctx, group, key, item)
+	})
+}
+
+func (s *ShardedCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	return s.each(key, false, func(c Cache) error {
+		return c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item)
+	})
+}
+
+func (s *ShardedCache) DeleteKey(ctx context.Context, key string) error {
+	return s.each(key, true, func(c Cache) error {
+		return c.DeleteKey(ctx, key)
+	})
+}
+
+func (s *ShardedCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return s.each(key, true, func(c Cache) error {
+		return c.Touch(ctx, key, ttl)
+	})
+}
+
+func (s *ShardedCache) Persist(ctx context.Context, key string) error {
+	return s.each(key, true, func(c Cache) error {
+		return c.Persist(ctx, key)
+	})
+}
+
+// GetCache reads from the owners in order, a miss on the primary falls back to the replicas.
+func (s *ShardedCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	var err error = ErrCacheMiss
+	for _, shard := range s.owners(key) {
+		v, e := shard.Cache.GetCache(ctx, group, key)
+		shard.observe(e)
+		if e == nil {
+			return v, nil
+		}
+		if !errors.Is(e, ErrCacheMiss) {
+			err = multierr.Combine(err, e)
+		}
+	}
+	return nil, err
+}
+
+func (s *ShardedCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var err error = ErrCacheMiss
+	for _, shard := range s.owners(key) {
+		ttl, e := shard.Cache.TTL(ctx, key)
+		shard.observe(e)
+		if e == nil {
+			return ttl, nil
+		}
+		if !errors.Is(e, ErrCacheMiss) {
+			err = multierr.Combine(err, e)
+		}
+	}
+	return 0, err
+}
+
+// each runs fn on every owner of key. Writes succeed once an owner stored them, strict calls fail when any owner
+// failed so deletes and expirations are not reported done while a replica still serves the old entry.
+// Misses only fail when no owner had the key.
+func (s *ShardedCache) each(key string, strict bool, fn func(c Cache) error) error {
+	owners := s.owners(key)
+	if len(owners) == 0 {
+		return ErrNoShards
+	}
+	var err error
+	var success bool
+	for _, shard := range owners {
+		e := fn(shard.Cache)
+		shard.observe(e)
+		switch {
+		case e == nil:
+			success = true
+		case !errors.Is(e, ErrCacheMiss):
+			err = multierr.Combine(err, fmt.Errorf("shard %s: %w", shard.name, e))
+		}
+	}
+	if err != nil && (strict || !success) {
+		return err
+	}
+	if !success {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// Shard is a node of a ShardedCache with its health.
+// Keys owned by other nodes are read and written through the ring so group checks see one logical cache.
+type Shard struct {
+	Cache
+	name    string
+	ring    *ShardedCache
+	healthy atomic.Bool
+	lastErr atomic.Pointer[error]
+}
+
+func (s *Shard) Name() string {
+	return s.name
+}
+
+func (s *Shard) Healthy() bool {
+	return s.healthy.Load()
+}
+
+// LastError returns the error that made the shard unhealthy.
+func (s *Shard) LastError() error {
+	if err := s.lastErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func (s *Shard) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	if !s.owns(key) {
+		return s.ring.GetCache(ctx, group, key)
+	}
+	v, err := s.Cache.GetCache(ctx, group, key)
+	s.observe(err)
+	return v, err
+}
+
+func (s *Shard) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if !s.owns(key) {
+		return s.ring.TTL(ctx, key)
+	}
+	ttl, err := s.Cache.TTL(ctx, key)
+	s.observe(err)
+	return ttl, err
+}
+
+func (s *Shard) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	if !s.owns(key) {
+		return s.ring.SetCache(ctx, group, key, item)
+	}
+	return s.observe(s.Cache.SetCache(ctx, group, key, item))
+}
+
+func (s *Shard) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	if !s.owns(key) {
+		return s.ring.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item)
+	}
+	return s.observe(s.Cache.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item))
+}
+
+func (s *Shard) DeleteKey(ctx context.Context, key string) error {
+	if !s.owns(key) {
+		return s.ring.DeleteKey(ctx, key)
+	}
+	return s.observe(s.Cache.DeleteKey(ctx, key))
+}
+
+func (s *Shard) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if !s.owns(key) {
+		return s.ring.Touch(ctx, key, ttl)
+	}
+	return s.observe(s.Cache.Touch(ctx, key, ttl))
+}
+
+func (s *Shard) Persist(ctx context.Context, key string) error {
+	if !s.owns(key) {
+		return s.ring.Persist(ctx, key)
+	}
+	return s.observe(s.Cache.Persist(ctx, key))
+}
+
+func (s *Shard) owns(key string) bool {
+	return s.ring.Node(key) == s.name
+}
+
+func (s *Shard) GetParentCaches() map[string]Cache {
+	return map[string]Cache{}
+}
+
+// observe records the health from the result of a call and returns err.
+func (s *Shard) observe(err error) error {
+	if err == nil || errors.Is(err, ErrCacheMiss) || errors.Is(err, ErrNotSupported) {
+		s.healthy.Store(true)
+		return err
+	}
+	s.lastErr.Store(&err)
+	s.healthy.Store(false)
+	return err
+}
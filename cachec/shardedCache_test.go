@@ -0,0 +1,143 @@
+package cachec
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShardNode() Cache {
+	return NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+}
+
+func TestShardedCacheRemapping(t *testing.T) {
+	s := NewShardedCache(map[string]Cache{"a": newShardNode(), "b": newShardNode(), "c": newShardNode()})
+	const total = 10000
+	before := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < total; i++ {
+		key := strconv.Itoa(i)
+		before[key] = s.Node(key)
+		counts[before[key]]++
+	}
+	for node, count := range counts {
+		assert.InDelta(t, total/3, count, total/10, node)
+	}
+
+	s.AddNode("d", newShardNode())
+	moved := 0
+	for key, node := range before {
+		if n := s.Node(key); n != node {
+			assert.Equal(t, "d", n)
+			moved++
+		}
+	}
+	assert.InDelta(t, total/4, moved, total/10)
+
+	s.RemoveNode("d")
+	for key, node := range before {
+		assert.Equal(t, node, s.Node(key))
+	}
+}
+
+func TestShardedCacheReplication(t *testing.T) {
+	ctx := context.Background()
+	nodes := map[string]*ChaosCache{}
+	caches := map[string]Cache{}
+	for _, name := range []string{"a", "b", "c"} {
+		nodes[name] = NewChaosCache(newShardNode(), ChaosConfig{Seed: 1})
+		nodes[name].Disable()
+		caches[name] = nodes[name]
+	}
+	s := NewShardedCache(caches, WithReplicas(1))
+	assert.NoError(t, s.SetCache(ctx, "", "key", []byte("value")))
+
+	primary := s.Node("key")
+	nodes[primary].SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 1}})
+	nodes[primary].Enable()
+
+	v, err := s.GetCache(ctx, "", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+
+	shard := s.GetParentCaches()[primary].(*Shard)
+	assert.False(t, shard.Healthy())
+	assert.ErrorIs(t, shard.LastError(), ErrChaos)
+
+	nodes[primary].Disable()
+	assert.NoError(t, s.Ping(ctx))
+	assert.True(t, shard.Healthy())
+}
+
+func TestShardedCacheGroups(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	s := NewShardedCache(map[string]Cache{"a": newShardNode(), "b": newShardNode(), "c": newShardNode()})
+	ctx := ContextWithCache(context.Background(), s)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, Set[int](ctx, "group", strconv.Itoa(i), i))
+	}
+	for i := 0; i < 10; i++ {
+		v, err := Get[int](ctx, "group", strconv.Itoa(i))
+		assert.NoError(t, err)
+		if err == nil {
+			assert.Equal(t, i, *v)
+		}
+	}
+}
+
+func TestShardedCacheStrictWrites(t *testing.T) {
+	ctx := context.Background()
+	nodes := map[string]*ChaosCache{}
+	caches := map[string]Cache{}
+	for _, name := range []string{"a", "b", "c"} {
+		nodes[name] = NewChaosCache(newShardNode(), ChaosConfig{Seed: 1})
+		nodes[name].Disable()
+		caches[name] = nodes[name]
+	}
+	s := NewShardedCache(caches, WithReplicas(1))
+	assert.NoError(t, s.SetCache(ctx, "", "key", []byte("value")))
+
+	owners := s.lookup("key")
+	replica := nodes[owners[1].name]
+	replica.SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdDELETE: 1, CacheCmdTOUCH: 1, CacheCmdSET: 1}})
+	replica.Enable()
+
+	assert.NoError(t, s.SetCache(ctx, "", "key", []byte("value")), "writes succeed on any owner")
+	assert.ErrorIs(t, s.Touch(ctx, "key", time.Minute), ErrChaos)
+	assert.ErrorIs(t, s.DeleteKey(ctx, "key"), ErrChaos, "a replica still holding the key fails the delete")
+	replica.Disable()
+	v, err := s.GetCache(ctx, "", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+
+	assert.NoError(t, s.DeleteKey(ctx, "key"))
+	assert.ErrorIs(t, s.Touch(ctx, "key", time.Minute), ErrCacheMiss)
+}
+
+func TestShardRouting(t *testing.T) {
+	ctx := context.Background()
+	s := NewShardedCache(map[string]Cache{"a": newShardNode(), "b": newShardNode()})
+	var key string
+	for i := 0; key == ""; i++ {
+		if s.Node(strconv.Itoa(i)) == "b" {
+			key = strconv.Itoa(i)
+		}
+	}
+	a := s.GetParentCaches()["a"].(*Shard)
+	b := s.GetParentCaches()["b"].(*Shard)
+
+	assert.NoError(t, a.SetCache(ctx, "", key, []byte("value")))
+	v, err := b.Cache.GetCache(ctx, "", key)
+	assert.NoError(t, err, "writes of keys owned by other nodes go through the ring")
+	assert.Equal(t, []byte("value"), v)
+	_, err = a.Cache.GetCache(ctx, "", key)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, a.DeleteKey(ctx, key))
+	_, err = b.GetCache(ctx, "", key)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
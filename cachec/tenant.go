@@ -0,0 +1,181 @@
+package cachec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const CTX_TENANT = "cache_tenant_ctx"
+
+// DefaultGenerationTTL is how long a client reuses the key generation of a tenant before reading it again.
+const DefaultGenerationTTL = time.Second
+
+var ErrTenantGeneration = errors.New("failed reading tenant key generation")
+
+// TenantResolver returns the tenant of the request, an empty tenant leaves keys unscoped.
+type TenantResolver func(ctx context.Context) string
+
+func ContextWithTenantResolver(ctx context.Context, resolver TenantResolver) context.Context {
+	return context.WithValue(ctx, CTX_TENANT, resolver) //nolint:staticcheck
+}
+
+// ContextWithTenant scopes every key, group and invalidation made with ctx to tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return ContextWithTenantResolver(ctx, func(context.Context) string {
+		return tenant
+	})
+}
+
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if resolver, ok := ctx.Value(CTX_TENANT).(TenantResolver); ok && resolver != nil {
+		return resolver(ctx)
+	}
+	return ""
+}
+
+// TenantGroup returns the group name used by the monitor for the tenant in ctx.
+func TenantGroup(ctx context.Context, group string) string {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return group
+	}
+	return tenantScope(tenant) + ":" + group
+}
+
+func tenantScope(tenant string) string {
+	return fmt.Sprintf("tenant[%s]", tenant)
+}
+
+// FlushTenant drops everything cached for the tenant through the default client.
+func FlushTenant(ctx context.Context, tenant string) error {
+	return Default().FlushTenant(ctx, tenant)
+}
+
+// FlushTenant starts a new key generation for the tenant, entries of the previous generation are never read again and expire.
+// Other clients keep using the previous generation for up to their generation TTL.
+func (c *Client) FlushTenant(ctx context.Context, tenant string) error {
+	if _, err := c.newGeneration(c.context(ctx), tenant); err != nil {
+		return err
+	}
+	return c.Monitor().Publish(ctx, Event{Type: EventFlush, Group: tenantScope(tenant)})
+}
+
+// generation returns the key generation of the tenant. A missing generation, never set or evicted, is replaced by
+// a new one so entries of an earlier generation cannot be read again.
+func (c *Client) generation(ctx context.Context, tenant string) (int64, error) {
+	if g, ok := c.generations.get(tenant); ok {
+		return g, nil
+	}
+	ctx = c.context(ctx)
+	data, err := c.Cache(ctx).GetCache(ctx, GroupPrefix, c.generationKey(tenant))
+	if errors.Is(err, ErrCacheMiss) {
+		return c.newGeneration(ctx, tenant)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrTenantGeneration, err)
+	}
+	var g int64
+	if err := json.Unmarshal(data, &g); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrTenantGeneration, err)
+	}
+	c.generations.set(tenant, g)
+	return g, nil
+}
+
+func (c *Client) newGeneration(ctx context.Context, tenant string) (int64, error) {
+	cache := c.Cache(ctx)
+	key := c.generationKey(tenant)
+	g := time.Now().UnixNano()
+	data, err := json.Marshal(g)
+	if err != nil {
+		return 0, err
+	}
+	if err := cache.SetCache(ctx, GroupPrefix, key, data); err != nil {
+		return 0, err
+	}
+	if err := cache.Persist(ctx, key); err != nil && !errors.Is(err, ErrNotSupported) {
+		return 0, err
+	}
+	c.generations.set(tenant, g)
+	return g, nil
+}
+
+func (c *Client) generationKey(tenant string) string {
+	return c.keys("tenant", GroupPrefix, tenantScope(tenant), "generation")
+}
+
+// groupKey builds the key of an entry in group, caches using the group key layout get the tenant group as prefix.
+func (c *Client) groupKey(ctx context.Context, cache Cache, typeName, group, key string) (string, error) {
+	k, err := c.KeyCtx(ctx, typeName, group, key)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := groupCache(cache); !ok || group == "" {
+		return k, nil
+	}
+	return GroupKeyPrefix(TenantGroup(ctx, group)) + k, nil
+}
+
+// KeyCtx builds the cache key like Key, adding the tenant and its generation when ctx has a tenant.
+// It fails with ErrTenantGeneration when the generation cannot be read, so callers skip the cache instead of
+// reading entries of a flushed generation.
+func (c *Client) KeyCtx(ctx context.Context, typeName string, keys ...string) (string, error) {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return c.keys(typeName, keys...), nil
+	}
+	g, err := c.generation(ctx, tenant)
+	if err != nil {
+		return "", err
+	}
+	scope := tenantScope(tenant) + "@" + strconv.FormatInt(g, 10)
+	return c.keys(typeName, append([]string{scope}, keys...)...), nil
+}
+
+// generations caches the key generation of each tenant for a short time, so keys do not cost a cache read each.
+type generations struct {
+	mutex   *sync.Mutex
+	ttl     time.Duration
+	entries map[string]generation
+}
+
+type generation struct {
+	value   int64
+	expires time.Time
+}
+
+func newGenerations(ttl time.Duration) *generations {
+	return &generations{
+		mutex:   &sync.Mutex{},
+		ttl:     ttl,
+		entries: map[string]generation{},
+	}
+}
+
+func (g *generations) get(tenant string) (int64, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	e, found := g.entries[tenant]
+	if !found || time.Now().After(e.expires) {
+		delete(g.entries, tenant)
+		return 0, false
+	}
+	return e.value, true
+}
+
+func (g *generations) set(tenant string, value int64) {
+	if g.ttl <= 0 {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.entries[tenant] = generation{value: value, expires: time.Now().Add(g.ttl)}
+}
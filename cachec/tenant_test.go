@@ -0,0 +1,169 @@
+package cachec
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	ctx := ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	a := ContextWithTenant(ctx, "a")
+	b := ContextWithTenantResolver(ctx, func(context.Context) string { return "b" })
+
+	keyA, err := GetKeyCtx[string](a, "users", "1")
+	assert.NoError(t, err)
+	keyB, err := GetKeyCtx[string](b, "users", "1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, keyA, keyB)
+	key, err := GetKeyCtx[string](ctx, "users", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, GetKey[string]("users", "1"), key)
+	assert.Equal(t, "tenant[a]:users", TenantGroup(a, "users"))
+
+	assert.NoError(t, Set[string](a, "users", "1", "alice"))
+	assert.NoError(t, Set[string](b, "users", "1", "bob"))
+	var updated time.Time
+	updated, err = GlobalCacheMonitor.LastUpdated(a, "users")
+	assert.NoError(t, err)
+	assert.False(t, updated.IsZero())
+	updated, err = GlobalCacheMonitor.LastUpdated(ctx, "users")
+	assert.NoError(t, err)
+	assert.True(t, updated.IsZero())
+
+	_, err = Get[string](ctx, "users", "1")
+	assert.Error(t, err)
+
+	v, err := Get[string](a, "users", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", *v)
+	v, err = Get[string](b, "users", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", *v)
+
+	assert.NoError(t, FlushTenant(ctx, "a"))
+	_, err = Get[string](a, "users", "1")
+	assert.Error(t, err)
+	v, err = Get[string](b, "users", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", *v)
+
+	assert.NoError(t, Set[string](a, "users", "1", "alice"))
+	v, err = Get[string](a, "users", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", *v)
+}
+
+func TestTenantWatch(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	ctx, cancel := context.WithCancel(ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")))
+	defer cancel()
+	a := ContextWithTenant(ctx, "a")
+	events, err := Watch(a, "users")
+	assert.NoError(t, err)
+
+	assert.NoError(t, Set[string](ContextWithTenant(ctx, "b"), "users", "1", "bob"))
+	assert.NoError(t, Set[string](a, "users", "1", "alice"))
+	select {
+	case e := <-events:
+		assert.Equal(t, "tenant[a]:users", e.Group)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestTenantGenerationFailsClosed(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	node := NewChaosCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), ChaosConfig{Seed: 1})
+	node.Disable()
+	c := NewClient(WithCache(node), WithGenerationTTL(0))
+	ctx := ContextWithTenant(context.Background(), "a")
+
+	assert.NoError(t, c.Set(ctx, "users", "1", "alice"))
+	var v string
+	assert.NoError(t, c.Get(ctx, "users", "1", &v))
+	assert.Equal(t, "alice", v)
+
+	assert.NoError(t, node.DeleteKey(ctx, c.generationKey("a")))
+	assert.ErrorIs(t, c.Get(ctx, "users", "1", &v), ErrCacheMiss, "an evicted generation starts a new one")
+
+	assert.NoError(t, c.Set(ctx, "users", "1", "alice"))
+	node.SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 1}})
+	node.Enable()
+	assert.Error(t, c.Get(ctx, "users", "1", &v))
+	_, err := c.KeyCtx(ctx, "string", "users", "1")
+	assert.ErrorIs(t, err, ErrTenantGeneration)
+	assert.ErrorIs(t, c.Set(ctx, "users", "2", "bob"), ErrTenantGeneration)
+}
+
+func TestTenantGenerationTTL(t *testing.T) {
+	GlobalCacheMonitor = NewMonitor()
+	node := NewChaosCache(NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""), ChaosConfig{Seed: 1})
+	node.Disable()
+	c := NewClient(WithCache(node), WithGenerationTTL(time.Minute))
+	ctx := ContextWithTenant(context.Background(), "a")
+
+	first, err := c.KeyCtx(ctx, "string", "users", "1")
+	assert.NoError(t, err)
+	node.SetConfig(ChaosConfig{Seed: 1, ErrorRate: map[CacheCmd]float64{CacheCmdGET: 1}})
+	node.Enable()
+	second, err := c.KeyCtx(ctx, "string", "users", "1")
+	assert.NoError(t, err, "the generation is reused without reading the cache")
+	assert.Equal(t, first, second)
+	node.Disable()
+
+	assert.NoError(t, c.FlushTenant(ctx, "a"))
+	flushed, err := c.KeyCtx(ctx, "string", "users", "1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, flushed, "flushes replace the local generation")
+}
+
+// TestTenantSharedLoads checks the in-process dedup and memo maps, they are keyed by the tenant scoped cache keys.
+func TestTenantSharedLoads(t *testing.T) {
+	ctx := ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	tenants := []string{"a", "b"}
+	started := make(chan struct{}, len(tenants))
+	release := make(chan struct{})
+	registry := NewLoaderRegistry()
+	RegisterLoader[string](registry, "users", 0, func(ctx context.Context, key string) (string, error) {
+		started <- struct{}{}
+		<-release
+		return TenantFromContext(ctx) + "-" + key, nil
+	})
+	tiered := NewTieredCache(registry, NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+
+	wg := &sync.WaitGroup{}
+	results := make([]string, len(tenants))
+	for i, tenant := range tenants {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := Peek[string](ContextWithTenant(ctx, tenant), tiered, "users", "1")
+			if assert.NoError(t, err) {
+				results[i] = *v
+			}
+		}()
+	}
+	for range tenants {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Error("tenants share a load")
+		}
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"a-1", "b-1"}, results)
+
+	rc := NewRequestCache(tiered)
+	for _, tenant := range tenants {
+		v, err := Peek[string](ContextWithTenant(ctx, tenant), rc, "users", "1")
+		assert.NoError(t, err)
+		assert.Equal(t, tenant+"-1", *v)
+	}
+}
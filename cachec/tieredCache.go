@@ -2,15 +2,23 @@ package cachec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
 )
 
 var _ Cache = &TieredCache{}
+var _ GroupCache = &TieredCache{}
+
+// groupTTLGetter is implemented by getters that set how long the values they load are cached, like LoaderRegistry.
+type groupTTLGetter interface {
+	GroupTTL(group string) time.Duration
+}
 
 type TieredCache struct {
 	cachePool []Cache
@@ -43,34 +51,22 @@ func (t *TieredCache) GetName() string {
 	return fmt.Sprintf("TIEREDCAHCE_%s", strings.Join(pool, "-"))
 }
 func (t *TieredCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
-	var err error
-	var success bool
-	for _, c := range t.cachePool {
-		if e := c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item); e == nil {
-			success = true
-		} else {
-			err = multierr.Combine(err, e)
-		}
-	}
-	if success {
-		return nil
-	}
+	ctx, span := t.startSpan(ctx, CacheCmdSET, group)
+	defer span.End()
+	err := t.each(func(c Cache) error {
+		return c.SetCacheWithExpiration(ctx, cacheTimeout, group, key, item)
+	})
+	recordSpanError(span, err)
 	return err
 }
 
 func (t *TieredCache) DeleteKey(ctx context.Context, key string) error {
-	var err error
-	var success bool
-	for _, c := range t.cachePool {
-		if e := c.DeleteKey(ctx, key); e == nil {
-			success = true
-		} else {
-			err = multierr.Combine(err, e)
-		}
-	}
-	if success {
-		return nil
-	}
+	ctx, span := t.startSpan(ctx, CacheCmdDELETE, "")
+	defer span.End()
+	err := t.each(func(c Cache) error {
+		return c.DeleteKey(ctx, key)
+	})
+	recordSpanError(span, err)
 	return err
 }
 
@@ -89,45 +85,179 @@ func (t *TieredCache) Close() {
 }
 
 func (t *TieredCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
-	var err error
-	var success bool
-	for _, c := range t.cachePool {
-		if e := c.SetCache(ctx, group, key, item); e == nil {
-			success = true
-		} else {
-			err = multierr.Combine(err, e)
-		}
-	}
-	if success {
-		return nil
-	}
+	ctx, span := t.startSpan(ctx, CacheCmdSET, group)
+	defer span.End()
+	err := t.each(func(c Cache) error {
+		return c.SetCache(ctx, group, key, item)
+	})
+	recordSpanError(span, err)
 	return err
 }
 
+// GetCache reads from the first tier holding the key and backfills the tiers that missed.
+// Values from a tier are backfilled for the time they have left in that tier. Values from a getter with a GroupTTL,
+// like LoaderRegistry, and tier values without a known expiration are stored for the ttl of the group.
+// The span records the index of the tier that answered, the getter counts as the tier after the pool.
 func (t *TieredCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	ctx, span := t.startSpan(ctx, CacheCmdGET, group)
+	defer span.End()
 	var missedCacheList []Cache
 	var v []byte
 	var err error
+	var ttl time.Duration
 	defer func() {
 		for _, c := range missedCacheList {
+			if ttl > 0 {
+				_ = c.SetCacheWithExpiration(ctx, ttl, group, key, v)
+				continue
+			}
 			_ = c.SetCache(ctx, group, key, v)
 		}
 	}()
-	for _, c := range t.cachePool {
+	for i, c := range t.cachePool {
 		v, err = c.GetCache(ctx, group, key)
 		if err != nil || v == nil {
 			missedCacheList = append(missedCacheList, c)
 			continue
 		}
+		if len(missedCacheList) > 0 {
+			ttl = t.backfillTTL(ctx, c, group, key)
+		}
+		span.SetAttributes(AttrHit.Bool(true), AttrTierHit.Int(i), AttrValueSize.Int(len(v)))
 		return v, nil
 	}
 	if t.getter == nil {
+		missedCacheList = []Cache{}
+		span.SetAttributes(AttrHit.Bool(false))
 		return nil, ErrCacheMiss
 	}
 	v, err = t.getter.GetCache(ctx, group, key)
-	if err != nil {
+	if err != nil || v == nil {
 		missedCacheList = []Cache{}
+		if err == nil {
+			err = ErrCacheMiss
+		}
+		span.SetAttributes(AttrHit.Bool(false))
+		recordSpanError(span, err)
 		return nil, err
 	}
+	ttl = t.groupTTL(group)
+	span.SetAttributes(AttrHit.Bool(true), AttrTierHit.Int(len(t.cachePool)), AttrValueSize.Int(len(v)))
 	return v, nil
 }
+
+// backfillTTL returns how long a value read from tier c is stored in the tiers that missed it, so backfilled copies
+// do not outlive the entry they were copied from.
+func (t *TieredCache) backfillTTL(ctx context.Context, c Cache, group, key string) time.Duration {
+	if remaining, err := c.TTL(ctx, key); err == nil && remaining > 0 {
+		return remaining
+	}
+	return t.groupTTL(group)
+}
+
+// groupTTL returns the ttl the getter sets for group, 0 when the tier defaults apply.
+func (t *TieredCache) groupTTL(group string) time.Duration {
+	if g, ok := t.getter.(groupTTLGetter); ok {
+		return g.GroupTTL(group)
+	}
+	return 0
+}
+
+// TTL returns the remaining time to live from the first tier that can answer.
+func (t *TieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var err error = ErrCacheMiss
+	for _, c := range t.cachePool {
+		ttl, e := c.TTL(ctx, key)
+		if e == nil {
+			return ttl, nil
+		}
+		if !errors.Is(e, ErrCacheMiss) && !errors.Is(e, ErrNotSupported) {
+			err = multierr.Combine(err, e)
+		}
+	}
+	return 0, err
+}
+
+func (t *TieredCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return t.each(func(c Cache) error {
+		return c.Touch(ctx, key, ttl)
+	})
+}
+
+func (t *TieredCache) Persist(ctx context.Context, key string) error {
+	return t.each(func(c Cache) error {
+		return c.Persist(ctx, key)
+	})
+}
+
+func (t *TieredCache) each(fn func(c Cache) error) error {
+	var err error
+	var success bool
+	for _, c := range t.cachePool {
+		if e := fn(c); e == nil {
+			success = true
+		} else {
+			err = multierr.Combine(err, e)
+		}
+	}
+	if success {
+		return nil
+	}
+	return err
+}
+
+func (t *TieredCache) startSpan(ctx context.Context, cmd CacheCmd, group string) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, "cachec.tiered."+strings.ToLower(string(cmd)))
+	span.SetAttributes(AttrBackend.String("tiered"), AttrCache.String(t.GetName()), AttrGroup.String(group), AttrCmd.String(string(cmd)))
+	return ctx, span
+}
+
+// GroupKeyLayout reports whether any tier uses the group key layout, the tiers then share the grouped keys.
+func (t *TieredCache) GroupKeyLayout() bool {
+	for _, c := range t.cachePool {
+		if _, ok := groupCache(c); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupKeys returns the keys of group found by the tiers using the group key layout.
+func (t *TieredCache) GroupKeys(ctx context.Context, group string) ([]string, error) {
+	seen := map[string]struct{}{}
+	var keys []string
+	var err error
+	for _, c := range t.cachePool {
+		gc, ok := groupCache(c)
+		if !ok {
+			continue
+		}
+		found, e := gc.GroupKeys(ctx, group)
+		err = multierr.Combine(err, e)
+		for _, k := range found {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys, err
+}
+
+// DeleteGroup deletes group from the tiers using the group key layout and the keys they found from the other tiers.
+func (t *TieredCache) DeleteGroup(ctx context.Context, group string) (int, error) {
+	keys, err := t.GroupKeys(ctx, group)
+	deleted := 0
+	for _, c := range t.cachePool {
+		if gc, ok := groupCache(c); ok {
+			n, e := gc.DeleteGroup(ctx, group)
+			deleted += n
+			err = multierr.Combine(err, e)
+			continue
+		}
+		for _, k := range keys {
+			err = multierr.Combine(err, c.DeleteKey(ctx, k))
+		}
+	}
+	return deleted, err
+}
@@ -0,0 +1,78 @@
+package cachec
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Seann-Moser/cutil/cachec"
+
+var (
+	AttrBackend   = attribute.Key("cache.backend")
+	AttrCache     = attribute.Key("cache.name")
+	AttrGroup     = attribute.Key("cache.group")
+	AttrCmd       = attribute.Key("cache.cmd")
+	AttrHit       = attribute.Key("cache.hit")
+	AttrTierHit   = attribute.Key("cache.tier_hit")
+	AttrValueSize = attribute.Key("cache.value_size")
+)
+
+func tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// TracingInterceptor starts a span for every operation with the backend, group, hit and value size.
+// Keys are left out of the attributes since they can hold user data.
+func TracingInterceptor(backend string) Interceptor {
+	return func(ctx context.Context, op *Operation, next Invoker) error {
+		ctx, span := tracer().Start(ctx, "cachec."+strings.ToLower(string(op.Cmd)), trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		span.SetAttributes(
+			AttrBackend.String(backend),
+			AttrCache.String(op.Cache),
+			AttrGroup.String(op.Group),
+			AttrCmd.String(string(op.Cmd)),
+		)
+		err := next(ctx, op)
+		if op.Cmd == CacheCmdGET {
+			span.SetAttributes(AttrHit.Bool(err == nil))
+		}
+		if span.IsRecording() {
+			span.SetAttributes(AttrValueSize.Int(op.ValueSize()))
+		}
+		recordSpanError(span, err)
+		return err
+	}
+}
+
+// recordSpanError marks the span as failed, misses are not errors.
+func recordSpanError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, ErrCacheMiss) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// startGetSet starts the span around a read-through lookup, the backend calls and the loader are its children.
+func startGetSet(ctx context.Context, name, group string) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, "cachec."+name)
+	span.SetAttributes(AttrGroup.String(group))
+	return ctx, span
+}
+
+// load runs the loader of a read-through lookup in its own span.
+func load[T any](ctx context.Context, group string, gtr func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracer().Start(ctx, "cachec.load")
+	defer span.End()
+	span.SetAttributes(AttrGroup.String(group))
+	v, err := gtr(ctx)
+	recordSpanError(span, err)
+	return v, err
+}
@@ -0,0 +1,104 @@
+package cachec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+	return recorder
+}
+
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func endedSpans(recorder *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var output []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			output = append(output, span)
+		}
+	}
+	return output
+}
+
+func TestTracingInterceptor(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := context.Background()
+	c := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+
+	_, err := c.GetCache(ctx, "group", "key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.NoError(t, c.SetCache(ctx, "group", "key", "value"))
+	_, err = c.GetCache(ctx, "group", "key")
+	assert.NoError(t, err)
+
+	gets := endedSpans(recorder, "cachec.get")
+	assert.Len(t, gets, 2)
+	miss, hit := spanAttrs(gets[0]), spanAttrs(gets[1])
+	assert.Equal(t, "go-cache", miss[AttrBackend].AsString())
+	assert.Equal(t, "group", miss[AttrGroup].AsString())
+	assert.False(t, miss[AttrHit].AsBool())
+	assert.True(t, hit[AttrHit].AsBool())
+	assert.Equal(t, int64(7), hit[AttrValueSize].AsInt64())
+	assert.Len(t, endedSpans(recorder, "cachec.set"), 1)
+}
+
+func TestTieredCacheTracing(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := context.Background()
+	l1 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "l1")
+	l2 := NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "l2")
+	c := NewTieredCache(nil, l1, l2)
+	assert.NoError(t, l2.SetCache(ctx, "group", "key", "value"))
+
+	_, err := c.GetCache(ctx, "group", "key")
+	assert.NoError(t, err)
+	spans := endedSpans(recorder, "cachec.tiered.get")
+	assert.Len(t, spans, 1)
+	attrs := spanAttrs(spans[0])
+	assert.Equal(t, "tiered", attrs[AttrBackend].AsString())
+	assert.True(t, attrs[AttrHit].AsBool())
+	assert.Equal(t, int64(1), attrs[AttrTierHit].AsInt64())
+
+	for _, span := range endedSpans(recorder, "cachec.get") {
+		assert.Equal(t, spans[0].SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
+
+func TestGetSetTracing(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := ContextWithCache(context.Background(), NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+
+	v, err := GetSet[string](ctx, time.Minute, "tracing", "key", func(ctx context.Context) (string, error) {
+		return "loaded", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+
+	getSet := endedSpans(recorder, "cachec.GetSet")
+	loads := endedSpans(recorder, "cachec.load")
+	assert.Len(t, getSet, 1)
+	assert.Len(t, loads, 1)
+	assert.False(t, spanAttrs(getSet[0])[AttrHit].AsBool())
+	assert.Equal(t, getSet[0].SpanContext().SpanID(), loads[0].Parent().SpanID())
+}
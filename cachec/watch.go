@@ -0,0 +1,86 @@
+package cachec
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventSet    = EventType("SET")
+	EventDelete = EventType("DELETE")
+	EventFlush  = EventType("FLUSH")
+
+	watchBuffer = 64
+)
+
+type Event struct {
+	Type   EventType `json:"type"`
+	Group  string    `json:"group"`
+	Key    string    `json:"key,omitempty"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source,omitempty"`
+}
+
+// EventBroker carries events between processes, Subscribe blocks until ctx is done.
+type EventBroker interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(ctx context.Context, fn func(event Event)) error
+}
+
+// Watch returns the change events for a group from the GlobalCacheMonitor, an empty group watches every group.
+func Watch(ctx context.Context, group string) (<-chan Event, error) {
+	return GlobalCacheMonitor.Watch(ctx, group)
+}
+
+type watchers struct {
+	mutex *sync.RWMutex
+	subs  map[string]map[chan Event]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{
+		mutex: &sync.RWMutex{},
+		subs:  map[string]map[chan Event]struct{}{},
+	}
+}
+
+func (w *watchers) watch(ctx context.Context, group string) <-chan Event {
+	ch := make(chan Event, watchBuffer)
+	w.mutex.Lock()
+	if _, found := w.subs[group]; !found {
+		w.subs[group] = map[chan Event]struct{}{}
+	}
+	w.subs[group][ch] = struct{}{}
+	w.mutex.Unlock()
+	go func() {
+		<-ctx.Done()
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.subs[group], ch)
+		if len(w.subs[group]) == 0 {
+			delete(w.subs, group)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// dispatch never blocks the writer, events are dropped for watchers that fall behind.
+func (w *watchers) dispatch(event Event) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	for _, group := range []string{event.Group, ""} {
+		for ch := range w.subs[group] {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+		if event.Group == "" {
+			return
+		}
+	}
+}
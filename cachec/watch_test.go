@@ -0,0 +1,123 @@
+package cachec_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	redis "github.com/Seann-Moser/ociredis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/cachec/cachectest"
+)
+
+func nextEvent(t *testing.T, events <-chan cachec.Event) cachec.Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return cachec.Event{}
+}
+
+func TestWatch(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx, cancel := context.WithCancel(cachec.ContextWithCache(context.Background(), newGoCache(t)))
+	defer cancel()
+
+	events, err := cachec.Watch(ctx, "users")
+	assert.NoError(t, err)
+	all, err := cachec.Watch(ctx, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cachec.Set[string](ctx, "roles", "admin", "value"))
+	assert.NoError(t, cachec.Set[string](ctx, "users", "1", "value"))
+	e := nextEvent(t, events)
+	assert.Equal(t, cachec.EventSet, e.Type)
+	assert.Equal(t, "1", e.Key)
+
+	assert.NoError(t, cachec.Delete[string](ctx, "users", "1"))
+	assert.Equal(t, cachec.EventDelete, nextEvent(t, events).Type)
+
+	assert.NoError(t, cachec.GlobalCacheMonitor.DeleteCache(ctx, "users"))
+	assert.Equal(t, cachec.EventFlush, nextEvent(t, events).Type)
+
+	assert.Equal(t, "roles", nextEvent(t, all).Group)
+
+	cancel()
+	_, open := <-events
+	for open {
+		_, open = <-events
+	}
+}
+
+func TestWatchRedisBroker(t *testing.T) {
+	s := cachectest.StartRedis(t)
+	newBroker := func() *cachec.RedisBroker {
+		return cachec.NewRedisBroker(redis.NewClient(&redis.Options{Addr: s.Addr(), Context: context.Background()}), "")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	local := cachec.NewMonitor().(*cachec.CacheMonitorImpl)
+	remote := cachec.NewMonitor().(*cachec.CacheMonitorImpl)
+	local.UseBroker(ctx, newBroker())
+	remote.UseBroker(ctx, newBroker())
+
+	events, err := local.Watch(ctx, "config")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, remote.Publish(ctx, cachec.Event{Type: cachec.EventFlush, Group: "config"}))
+		select {
+		case e := <-events:
+			return e.Type == cachec.EventFlush && e.Group == "config"
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// flakyBroker fails the first subscriptions and every publish.
+type flakyBroker struct {
+	failures   int32
+	subscribed chan struct{}
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, event cachec.Event) error {
+	return errors.New("broker down")
+}
+
+func (b *flakyBroker) Subscribe(ctx context.Context, fn func(event cachec.Event)) error {
+	if atomic.AddInt32(&b.failures, -1) >= 0 {
+		return errors.New("broker down")
+	}
+	close(b.subscribed)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWatchBrokerFailures(t *testing.T) {
+	broker := &flakyBroker{failures: 2, subscribed: make(chan struct{})}
+	monitor := cachec.NewMonitor().(*cachec.CacheMonitorImpl)
+	cachec.GlobalCacheMonitor = monitor
+	ctx, cancel := context.WithCancel(cachec.ContextWithCache(context.Background(), newGoCache(t)))
+	defer cancel()
+	monitor.UseBroker(ctx, broker)
+
+	select {
+	case <-broker.subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed subscriptions are not retried")
+	}
+
+	assert.ErrorIs(t, monitor.Publish(ctx, cachec.Event{Type: cachec.EventFlush, Group: "config"}), cachec.ErrPublish)
+	assert.NoError(t, cachec.Set[string](ctx, "users", "1", "value"))
+	assert.NoError(t, cachec.Delete[string](ctx, "users", "1"), "the delete succeeded even though the event was not published")
+	_, err := cachec.Get[string](ctx, "users", "1")
+	assert.Error(t, err)
+}
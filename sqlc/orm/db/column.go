@@ -27,6 +27,7 @@ type Column struct {
 	GroupByName       string `json:"group_by_name"`
 	GroupByColumn     bool   `json:"group_by_column"`
 	OrderPriority     int    `json:"order_priority"`
+	RowCache          bool   `json:"row_cache"`
 
 	Type    string `json:"data_type"`
 	Default string `json:"default"`
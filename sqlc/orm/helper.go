@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/Seann-Moser/cutil/cachec"
 	"github.com/Seann-Moser/cutil/sqlc/orm/db"
 	"go.opencensus.io/tag"
 	"reflect"
@@ -13,10 +14,16 @@ import (
 )
 
 var (
+	// QueryNameTag is only inserted while the cachec OpenCensus bridge is enabled.
 	QueryNameTag = tag.MustNewKey("query_name")
 )
 
+// CtxWithQueryTag adds the query name to the metrics recorded with ctx.
 func CtxWithQueryTag(ctx context.Context, queryName string) context.Context {
+	ctx = cachec.ContextWithMetricAttributes(ctx, AttrQueryName.String(queryName))
+	if !cachec.OpenCensusBridgeEnabled() {
+		return ctx
+	}
 	newCtx, err := tag.New(ctx, tag.Insert(QueryNameTag, queryName))
 	if err != nil {
 		return ctx
@@ -0,0 +1,67 @@
+package orm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	meterName = "github.com/Seann-Moser/cutil/sqlc/orm"
+
+	MetricQueryDuration = "orm.query.duration"
+	MetricQueryCalls    = "orm.query.calls"
+
+	AttrQueryName = attribute.Key("query_name")
+	AttrTable     = attribute.Key("db.table")
+	AttrStatus    = attribute.Key("status")
+)
+
+var queryMetrics = &queryInstruments{mutex: &sync.Mutex{}}
+
+// queryInstruments are rebuilt when the cachec meter provider changes.
+type queryInstruments struct {
+	mutex    *sync.Mutex
+	provider metric.MeterProvider
+	duration metric.Float64Histogram
+	calls    metric.Int64Counter
+}
+
+func (q *queryInstruments) get() (metric.Float64Histogram, metric.Int64Counter, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	provider := cachec.MeterProvider()
+	if q.provider == provider && q.duration != nil {
+		return q.duration, q.calls, nil
+	}
+	meter := provider.Meter(meterName)
+	duration, err := meter.Float64Histogram(MetricQueryDuration, metric.WithDescription("The duration of queries in milliseconds"), metric.WithUnit("ms"))
+	if err != nil {
+		return nil, nil, err
+	}
+	calls, err := meter.Int64Counter(MetricQueryCalls, metric.WithDescription("The number of queries"))
+	if err != nil {
+		return nil, nil, err
+	}
+	q.provider, q.duration, q.calls = provider, duration, calls
+	return duration, calls, nil
+}
+
+// recordQuery records a query with the metric attributes of ctx, which include the query name.
+func recordQuery(ctx context.Context, table string, start time.Time, err error) {
+	duration, calls, e := queryMetrics.get()
+	if e != nil {
+		return
+	}
+	status := "OK"
+	if err != nil {
+		status = "ERR"
+	}
+	attrs := metric.WithAttributes(append(cachec.MetricAttributes(ctx), AttrTable.String(table), AttrStatus.String(status))...)
+	duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs)
+	calls.Add(ctx, 1, attrs)
+}
@@ -0,0 +1,45 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	cachec.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer cachec.SetMeterProvider(nil)
+
+	ctx := context.Background()
+	d := &rowDB{MockDB: db.NewMockDB(), rows: []interface{}{CachedRole{ID: "1", Name: "admin"}}}
+	table, err := NewTable[CachedRole]("metrics_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, table.InitializeTable(ctx, d))
+	q := QueryTable[CachedRole](table).SetName("roles")
+	_, err = q.Run(ctx, d)
+	assert.NoError(t, err)
+
+	rm := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	var calls metricdata.Sum[int64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == MetricQueryCalls {
+				calls = m.Data.(metricdata.Sum[int64])
+			}
+		}
+	}
+	assert.Len(t, calls.DataPoints, 1)
+	dp := calls.DataPoints[0]
+	assert.Equal(t, int64(1), dp.Value)
+	name, _ := dp.Attributes.Value(AttrQueryName)
+	assert.Equal(t, "roles", name.AsString())
+	tableName, _ := dp.Attributes.Value(AttrTable)
+	assert.Equal(t, table.FullTableName(), tableName.AsString())
+}
@@ -0,0 +1,232 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/multierr"
+)
+
+type txWritesCtxName string
+
+const (
+	txWritesCtx   = txWritesCtxName("tx-writes")
+	skipNotifyCtx = txWritesCtxName("skip-notify")
+)
+
+const (
+	dependentsKeyPrefix = "orm_table_dependents:"
+	// dependentsTTL is how long shared dependencies are kept, running queries share them again every
+	// dependentsShareInterval.
+	dependentsTTL           = time.Hour
+	dependentsShareInterval = 5 * time.Minute
+)
+
+var tableDependencies = &dependencies{
+	mutex:  &sync.RWMutex{},
+	deps:   map[string]map[string]struct{}{},
+	shared: map[[2]string]time.Time{},
+}
+
+type dependencies struct {
+	mutex *sync.RWMutex
+	deps  map[string]map[string]struct{}
+	// shared records when a table and the table it depends on were last written to the shared cache.
+	shared map[[2]string]time.Time
+}
+
+// RegisterTableDependency records that cached queries of table read dependsOn, writes to dependsOn then invalidate table.
+// Cached queries register their joined tables on Run and share them through the cache, so writes made by other
+// processes invalidate them too.
+func RegisterTableDependency(table, dependsOn string) {
+	if table == dependsOn {
+		return
+	}
+	tableDependencies.mutex.RLock()
+	_, found := tableDependencies.deps[dependsOn][table]
+	tableDependencies.mutex.RUnlock()
+	if found {
+		return
+	}
+	tableDependencies.mutex.Lock()
+	defer tableDependencies.mutex.Unlock()
+	if _, found := tableDependencies.deps[dependsOn]; !found {
+		tableDependencies.deps[dependsOn] = map[string]struct{}{}
+	}
+	tableDependencies.deps[dependsOn][table] = struct{}{}
+}
+
+// TableDependents returns the tables whose cached queries read table.
+func TableDependents(table string) []string {
+	tableDependencies.mutex.RLock()
+	defer tableDependencies.mutex.RUnlock()
+	var output []string
+	for t := range tableDependencies.deps[table] {
+		output = append(output, t)
+	}
+	sort.Strings(output)
+	return output
+}
+
+// shareTableDependency adds the dependency to the record of dependsOn in the context cache, at most once per
+// dependentsShareInterval. Concurrent writers can drop each other's tables, the record is read back and written
+// again until it holds table.
+func shareTableDependency(ctx context.Context, table, dependsOn string) {
+	if table == dependsOn {
+		return
+	}
+	pair := [2]string{table, dependsOn}
+	now := time.Now()
+	tableDependencies.mutex.Lock()
+	if now.Sub(tableDependencies.shared[pair]) < dependentsShareInterval {
+		tableDependencies.mutex.Unlock()
+		return
+	}
+	tableDependencies.shared[pair] = now
+	tableDependencies.mutex.Unlock()
+
+	for attempt := 0; attempt < 3; attempt++ {
+		dependents := sharedDependents(ctx, dependsOn)
+		if _, found := dependents[table]; found && attempt > 0 {
+			return
+		}
+		dependents[table] = struct{}{}
+		if err := cachec.SetWithExpiration[map[string]struct{}](ctx, dependentsTTL, cachec.GroupPrefix, dependentsKeyPrefix+dependsOn, dependents); err != nil {
+			break
+		}
+	}
+	// let the next query try again.
+	tableDependencies.mutex.Lock()
+	delete(tableDependencies.shared, pair)
+	tableDependencies.mutex.Unlock()
+}
+
+func sharedDependents(ctx context.Context, table string) map[string]struct{} {
+	dependents, err := cachec.Get[map[string]struct{}](ctx, cachec.GroupPrefix, dependentsKeyPrefix+table)
+	if err != nil || *dependents == nil {
+		return map[string]struct{}{}
+	}
+	return *dependents
+}
+
+// NotifyWrite invalidates the cached queries of table and of every table that depends on it, in this process or
+// shared through the cache in ctx.
+func NotifyWrite(ctx context.Context, table, operation string) error {
+	err := cachec.GlobalCacheMonitor.UpdateCache(ctx, table, operation)
+	dependents := sharedDependents(ctx, table)
+	for _, dependent := range TableDependents(table) {
+		dependents[dependent] = struct{}{}
+	}
+	for dependent := range dependents {
+		err = multierr.Combine(err, cachec.GlobalCacheMonitor.UpdateCache(ctx, dependent, operation))
+	}
+	return err
+}
+
+// WithoutWriteNotify returns a context whose table writes do not invalidate the cache,
+// for tables the cache itself is stored in.
+func WithoutWriteNotify(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipNotifyCtx, true)
+}
+
+func writeNotifySkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipNotifyCtx).(bool)
+	return skip
+}
+
+// notifyWrite is the single invalidation path for table writes.
+func (t *Table[T]) notifyWrite(ctx context.Context, operation string, rows ...T) {
+	if writeNotifySkipped(ctx) {
+		return
+	}
+	_ = NotifyWrite(ctx, t.FullTableName(), operation)
+	t.invalidateRows(ctx, rows...)
+}
+
+// notifyTxWrite holds the invalidation until the transaction commits when ctx carries TxWrites, like the ctx passed
+// to RunInTx. Writes of transactions begun by the caller invalidate immediately, a read made before the commit
+// may then cache the previous rows until the next write.
+func (t *Table[T]) notifyTxWrite(ctx context.Context, operation string, rows ...T) {
+	if writeNotifySkipped(ctx) {
+		return
+	}
+	w, ok := ctx.Value(txWritesCtx).(*TxWrites)
+	if !ok {
+		t.notifyWrite(ctx, operation, rows...)
+		return
+	}
+	w.add(func(ctx context.Context) {
+		t.notifyWrite(ctx, operation, rows...)
+	})
+}
+
+// TxWrites collects the invalidations of transactional writes so they run after commit.
+type TxWrites struct {
+	mutex   *sync.Mutex
+	pending []func(ctx context.Context)
+}
+
+// WithTxWrites returns a context whose Tx writes are held until Commit is called on the returned TxWrites.
+func WithTxWrites(ctx context.Context) (context.Context, *TxWrites) {
+	w := &TxWrites{mutex: &sync.Mutex{}}
+	return context.WithValue(ctx, txWritesCtx, w), w
+}
+
+func (w *TxWrites) add(fn func(ctx context.Context)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = append(w.pending, fn)
+}
+
+// Commit runs the held invalidations, call it after the transaction committed.
+func (w *TxWrites) Commit(ctx context.Context) {
+	w.mutex.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mutex.Unlock()
+	for _, fn := range pending {
+		fn(ctx)
+	}
+}
+
+// Discard drops the held invalidations of a rolled back transaction.
+func (w *TxWrites) Discard() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.pending = nil
+}
+
+type TxBeginner interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// RunInTx runs fn in a transaction, commits when fn succeeds and invalidates the written tables after the commit.
+func RunInTx(ctx context.Context, d TxBeginner, fn func(ctx context.Context, tx *sqlx.Tx) error) (err error) {
+	tx, err := d.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	txCtx, writes := WithTxWrites(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			writes.Discard()
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(txCtx, tx); err != nil {
+		writes.Discard()
+		return multierr.Combine(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		writes.Discard()
+		return err
+	}
+	writes.Commit(ctx)
+	return nil
+}
@@ -0,0 +1,152 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+	"github.com/jmoiron/sqlx"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyWrite(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB()}
+	roles, err := NewTable[CachedRole]("notify_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, roles.InitializeTable(ctx, d))
+	memberships, err := NewTable[Membership]("notify_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, memberships.InitializeTable(ctx, d))
+
+	q := QueryTable[CachedRole](roles).Join(memberships.GetColumns(), "LEFT")
+	q.registerDependencies(ctx)
+	assert.Equal(t, []string{roles.FullTableName()}, TableDependents(memberships.FullTableName()))
+	assert.Empty(t, TableDependents(roles.FullTableName()))
+
+	updated := func(table string) bool {
+		last, err := cachec.GlobalCacheMonitor.LastUpdated(ctx, table)
+		assert.NoError(t, err)
+		return !last.IsZero()
+	}
+	assert.NoError(t, memberships.Delete(ctx, nil, Membership{UserID: 1, GroupID: "a"}))
+	assert.True(t, updated(memberships.FullTableName()))
+	assert.True(t, updated(roles.FullTableName()))
+
+	other := map[string]db.Column{"id": {Name: "role_id", Table: "other", Dataset: "notify_dataset", Join: true, JoinName: "id", Delete: true}}
+	assert.NoError(t, roles.DeleteWithColumns(ctx, "notify_dataset.other", other, CachedRole{ID: "1"}))
+	assert.True(t, updated("notify_dataset.other"))
+}
+
+func TestTxWrites(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB()}
+	roles, err := NewTable[CachedRole]("tx_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, roles.InitializeTable(ctx, d))
+	updated := func() bool {
+		last, err := cachec.GlobalCacheMonitor.LastUpdated(ctx, roles.FullTableName())
+		assert.NoError(t, err)
+		return !last.IsZero()
+	}
+
+	txCtx, writes := WithTxWrites(ctx)
+	roles.notifyTxWrite(txCtx, "update", CachedRole{ID: "1"})
+	writes.Discard()
+	writes.Commit(ctx)
+	assert.False(t, updated())
+
+	txCtx, writes = WithTxWrites(ctx)
+	roles.notifyTxWrite(txCtx, "update", CachedRole{ID: "1"})
+	assert.False(t, updated())
+	writes.Commit(ctx)
+	assert.True(t, updated())
+}
+
+// txConn is a database/sql driver whose transactions and statements always succeed.
+type txConn struct{}
+
+func (txConn) Connect(context.Context) (driver.Conn, error) { return txConn{}, nil }
+func (txConn) Driver() driver.Driver                        { return txDriver{} }
+func (txConn) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (txConn) Close() error                                 { return nil }
+func (txConn) Begin() (driver.Tx, error)                    { return txConn{}, nil }
+func (txConn) Commit() error                                { return nil }
+func (txConn) Rollback() error                              { return nil }
+func (txConn) CheckNamedValue(*driver.NamedValue) error     { return nil }
+func (txConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type txDriver struct{}
+
+func (txDriver) Open(string) (driver.Conn, error) { return txConn{}, nil }
+
+func TestCallerTx(t *testing.T) {
+	reader := cachec.NewMonitor()
+	cachec.GlobalCacheMonitor = reader
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB()}
+	roles, err := NewTable[CachedRole]("caller_tx_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, roles.InitializeTable(ctx, d))
+	run := func() {
+		d.rows = []interface{}{CachedRole{ID: "1", Name: "admin"}}
+		_, err := QueryTable[CachedRole](roles).UseCache().Run(ctx, d)
+		assert.NoError(t, err)
+	}
+	get := func() {
+		d.rows = []interface{}{CachedRole{ID: "1", Name: "admin"}}
+		_, err := roles.GetByPrimary(ctx, nil, "1")
+		assert.NoError(t, err)
+	}
+	// the monitor reports groups it has not seen yet as updated.
+	for i := 0; i < 3; i++ {
+		run()
+	}
+	get()
+	queries := d.queries
+	run()
+	get()
+	assert.Equal(t, queries, d.queries)
+
+	// the write is made by another process sharing the cache.
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	conn := sqlx.NewDb(sql.OpenDB(txConn{}), "mysql")
+	tx, err := conn.BeginTxx(ctx, nil)
+	assert.NoError(t, err)
+	_, err = roles.UpdateTx(ctx, tx, CachedRole{ID: "1", Name: "owner"})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	cachec.GlobalCacheMonitor = reader
+
+	run()
+	assert.Equal(t, queries+1, d.queries, "writes of transactions committed by the caller invalidate the cached queries")
+	get()
+	assert.Equal(t, queries+2, d.queries, "and the cached rows")
+}
+
+func TestSharedTableDependencies(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	updated := func(table string) bool {
+		last, err := cachec.GlobalCacheMonitor.LastUpdated(ctx, table)
+		assert.NoError(t, err)
+		return !last.IsZero()
+	}
+
+	shareTableDependency(ctx, "shared_dataset.reports", "shared_dataset.users")
+	shareTableDependency(ctx, "shared_dataset.audits", "shared_dataset.users")
+	assert.Empty(t, TableDependents("shared_dataset.users"), "the dependencies are only in the cache")
+	assert.NoError(t, NotifyWrite(ctx, "shared_dataset.users", "update"))
+	assert.True(t, updated("shared_dataset.reports"), "writes in other processes invalidate shared dependents")
+	assert.True(t, updated("shared_dataset.audits"))
+}
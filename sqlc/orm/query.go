@@ -335,10 +335,14 @@ func (q *Query[T]) RunCtx(ctx context.Context) ([]*T, error) {
 	return q.Run(ctx, nil)
 }
 
-func (q *Query[T]) Run(ctx context.Context, db db.DB, args ...interface{}) ([]*T, error) {
+func (q *Query[T]) Run(ctx context.Context, db db.DB, args ...interface{}) (rows []*T, err error) {
 	if q.Err != nil {
 		return nil, q.Err
 	}
+	start := time.Now()
+	defer func() {
+		recordQuery(ctx, q.FromTable.FullTableName(), start, err)
+	}()
 	if q.Name != "" {
 		query, err := cachec.Get[string](ctx, "queries", q.Name)
 		if err == nil && *query != "" {
@@ -353,6 +357,9 @@ func (q *Query[T]) Run(ctx context.Context, db db.DB, args ...interface{}) ([]*T
 	}
 	ctx = CtxWithQueryTag(ctx, q.getName())
 	cacheKey := q.GetCacheKey(args...)
+	if q.Cache != nil || q.useCache {
+		q.registerDependencies(ctx)
+	}
 
 	if q.Cache != nil {
 		data, err := cachec.GetFromCache[[]*T](ctx, q.Cache, q.FromTable.FullTableName(), cacheKey)
@@ -382,6 +389,16 @@ func (q *Query[T]) Run(ctx context.Context, db db.DB, args ...interface{}) ([]*T
 	return data, nil
 }
 
+// registerDependencies records the joined tables so writes to them invalidate the cached results of this query.
+func (q *Query[T]) registerDependencies(ctx context.Context) {
+	for _, join := range q.JoinStmt {
+		for _, column := range join.Columns {
+			RegisterTableDependency(q.FromTable.FullTableName(), column.FullTableName())
+			shareTableDependency(ctx, q.FromTable.FullTableName(), column.FullTableName())
+		}
+	}
+}
+
 func (q *Query[T]) Args(args ...interface{}) map[string]interface{} {
 	whereArgs := map[string]interface{}{}
 	for _, where := range q.WhereStmts {
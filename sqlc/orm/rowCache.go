@@ -0,0 +1,173 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+)
+
+// WithRowCache caches rows read by primary key for ttl, 0 uses the cache default duration.
+// It has the same effect as the row_cache qc tag.
+func (t *Table[T]) WithRowCache(ttl time.Duration) *Table[T] {
+	t.rowCache = true
+	t.rowCacheTTL = ttl
+	rowCacheTables.register(t.FullTableName(), t)
+	return t
+}
+
+var rowCacheTables = &rowCacheRegistry{
+	mutex:  &sync.RWMutex{},
+	tables: map[string]rowCacheTable{},
+}
+
+// rowCacheTable is the type independent view of a table with a row cache, for writes made through other tables.
+type rowCacheTable interface {
+	// matchingRows reads the primary keys of the rows matching where and returns the invalidation of their rows.
+	matchingRows(ctx context.Context, d db.DB, where string, arg interface{}) (func(ctx context.Context), error)
+}
+
+// rowCacheRegistry maps the full table names to the last table created for them with a row cache.
+type rowCacheRegistry struct {
+	mutex  *sync.RWMutex
+	tables map[string]rowCacheTable
+}
+
+func (r *rowCacheRegistry) register(table string, t rowCacheTable) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tables[table] = t
+}
+
+func (r *rowCacheRegistry) get(table string) (rowCacheTable, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	t, found := r.tables[table]
+	return t, found
+}
+
+func (t *Table[T]) RowCacheEnabled() bool {
+	return t.rowCache
+}
+
+// RowCacheGroup is the cache group of the rows, it is separate from the table group so rows are only
+// invalidated by writes to the same primary key.
+func (t *Table[T]) RowCacheGroup() string {
+	return t.FullTableName() + ":rows"
+}
+
+// primaryColumns returns the primary columns in struct order.
+func (t *Table[T]) primaryColumns() []db.Column {
+	columns := t.GetPrimary()
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].ColumnOrder < columns[j].ColumnOrder
+	})
+	return columns
+}
+
+// GetByPrimary returns the row with the primary key values in struct order, composite keys pass one value per column.
+// Rows are read through the row cache when it is enabled.
+func (t *Table[T]) GetByPrimary(ctx context.Context, d db.DB, values ...interface{}) (*T, error) {
+	columns := t.primaryColumns()
+	if len(values) != len(columns) {
+		return nil, fmt.Errorf("table %s expects %d primary key values, got %d", t.FullTableName(), len(columns), len(values))
+	}
+	load := func() (*T, error) {
+		q := QueryTable[T](t)
+		for i, column := range columns {
+			q.Where(column, "=", "AND", 0, values[i])
+		}
+		return q.RunSingle(ctx, d)
+	}
+	if !t.rowCache {
+		return load()
+	}
+	key := rowKey(values)
+	cache := cachec.GetCacheFromContext(ctx)
+	if row, err := cachec.Peek[T](ctx, cache, t.RowCacheGroup(), key); err == nil {
+		return row, nil
+	}
+	row, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if t.rowCacheTTL > 0 {
+		_ = cachec.SetFromCacheWithExpiration[T](ctx, cache, t.rowCacheTTL, t.RowCacheGroup(), key, *row)
+	} else {
+		_ = cachec.SetFromCache[T](ctx, cache, t.RowCacheGroup(), key, *row)
+	}
+	return row, nil
+}
+
+// invalidateRows drops the cached rows for the primary keys of s, rows without a complete key are skipped.
+func (t *Table[T]) invalidateRows(ctx context.Context, s ...T) {
+	if !t.rowCache {
+		return
+	}
+	columns := t.primaryColumns()
+	for _, row := range s {
+		values, ok := primaryValues(columns, row)
+		if !ok {
+			continue
+		}
+		_ = cachec.Delete[T](ctx, t.RowCacheGroup(), rowKey(values))
+	}
+}
+
+func (t *Table[T]) matchingRows(ctx context.Context, d db.DB, where string, arg interface{}) (func(ctx context.Context), error) {
+	var primary []string
+	for _, column := range t.primaryColumns() {
+		primary = append(primary, column.Name)
+	}
+	matched, err := t.NamedSelect(ctx, d, fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(primary, ", "), t.FullTableName(), where), arg)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]T, 0, len(matched))
+	for _, row := range matched {
+		rows = append(rows, *row)
+	}
+	return func(ctx context.Context) {
+		t.invalidateRows(ctx, rows...)
+	}, nil
+}
+
+// primaryValues reads the primary key fields of row, columns are matched to the struct field they were parsed from
+// so the json tags of the row do not matter.
+func primaryValues(columns []db.Column, row interface{}) ([]interface{}, bool) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if column.ColumnOrder >= v.NumField() {
+			return nil, false
+		}
+		f := reflect.Indirect(v.Field(column.ColumnOrder))
+		if !f.IsValid() || (f.Kind() == reflect.String && f.Len() == 0) {
+			return nil, false
+		}
+		values[i] = f.Interface()
+	}
+	return values, true
+}
+
+// rowKey joins the values with their length as prefix, so values containing the separator cannot collide.
+func rowKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		s := fmt.Sprint(v)
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+	return b.String()
+}
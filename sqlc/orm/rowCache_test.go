@@ -0,0 +1,155 @@
+package orm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type CachedRole struct {
+	ID   string `json:"id" db:"id" qc:"primary;row_cache"`
+	Name string `json:"name" db:"name" qc:"update"`
+}
+
+type Membership struct {
+	UserID  int    `json:"user_id" db:"user_id" qc:"primary"`
+	GroupID string `json:"group_id" db:"group_id" qc:"primary"`
+	Role    string `json:"role" db:"role" qc:"update"`
+}
+
+// rowDB answers every query with its rows and counts the queries.
+type rowDB struct {
+	*db.MockDB
+	rows    []interface{}
+	queries int
+}
+
+func (r *rowDB) QueryContext(ctx context.Context, query string, args interface{}) (db.DBRow, error) {
+	r.queries++
+	return &mockRows{rows: r.rows}, nil
+}
+
+type mockRows struct {
+	rows []interface{}
+}
+
+func (m *mockRows) Next() bool {
+	return len(m.rows) > 0
+}
+
+func (m *mockRows) StructScan(i interface{}) error {
+	b, err := json.Marshal(m.rows[0])
+	if err != nil {
+		return err
+	}
+	m.rows = m.rows[1:]
+	return json.Unmarshal(b, i)
+}
+
+func TestTable_RowCache(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB(), rows: []interface{}{CachedRole{ID: "1", Name: "admin"}}}
+	table, err := NewTable[CachedRole]("test_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, table.InitializeTable(ctx, d))
+	assert.True(t, table.RowCacheEnabled())
+
+	get := func() *CachedRole {
+		d.rows = []interface{}{CachedRole{ID: "1", Name: "admin"}}
+		row, err := table.GetByPrimary(ctx, nil, "1")
+		assert.NoError(t, err)
+		return row
+	}
+	assert.Equal(t, "admin", get().Name)
+	assert.Equal(t, "admin", get().Name)
+	assert.Equal(t, 1, d.queries)
+
+	assert.NoError(t, table.Update(ctx, nil, CachedRole{ID: "1", Name: "owner"}))
+	get()
+	assert.Equal(t, 2, d.queries)
+	get()
+	assert.Equal(t, 2, d.queries)
+
+	assert.NoError(t, table.Delete(ctx, nil, CachedRole{ID: "1"}))
+	get()
+	assert.Equal(t, 3, d.queries)
+
+	memberships, err := NewTable[Membership]("test_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, memberships.InitializeTable(ctx, d))
+	byRole := map[string]db.Column{"role": {Name: "role", Table: table.Name, Dataset: table.Dataset, Join: true, JoinName: "role", Delete: true}}
+	d.rows = []interface{}{CachedRole{ID: "1"}}
+	assert.NoError(t, memberships.DeleteWithColumns(ctx, table.FullTableName(), byRole, Membership{Role: "admin"}))
+	assert.Equal(t, 4, d.queries, "the keys of the deleted rows are selected")
+	get()
+	assert.Equal(t, 5, d.queries, "rows deleted through other tables are invalidated")
+}
+
+func TestTable_RowCacheCompositeKey(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB()}
+	table, err := NewTable[Membership]("test_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, table.InitializeTable(ctx, d))
+	assert.False(t, table.RowCacheEnabled())
+	table.WithRowCache(time.Minute)
+
+	_, err = table.GetByPrimary(ctx, nil, 1)
+	assert.Error(t, err)
+
+	member := Membership{UserID: 1, GroupID: "a", Role: "owner"}
+	for i := 0; i < 2; i++ {
+		d.rows = []interface{}{member}
+		row, err := table.GetByPrimary(ctx, nil, 1, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, member, *row)
+	}
+	assert.Equal(t, 1, d.queries)
+
+	_, err = table.Upsert(ctx, nil, member)
+	assert.NoError(t, err)
+	d.rows = []interface{}{member}
+	_, err = table.GetByPrimary(ctx, nil, 1, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, d.queries)
+}
+
+type TaggedRole struct {
+	Key  string `json:"key" db:"role_key" qc:"primary;row_cache"`
+	Name string `db:"name" qc:"update"`
+}
+
+func TestTable_RowCacheDBTags(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := cachec.ContextWithCache(context.Background(), cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, ""))
+	d := &rowDB{MockDB: db.NewMockDB()}
+	table, err := NewTable[TaggedRole]("test_dataset", QueryTypeSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, table.InitializeTable(ctx, d))
+
+	get := func() {
+		d.rows = []interface{}{TaggedRole{Key: "1", Name: "admin"}}
+		_, err := table.GetByPrimary(ctx, nil, "1")
+		assert.NoError(t, err)
+	}
+	get()
+	get()
+	assert.Equal(t, 1, d.queries)
+	assert.NoError(t, table.Update(ctx, nil, TaggedRole{Key: "1", Name: "owner"}))
+	get()
+	assert.Equal(t, 2, d.queries, "rows are invalidated when the json and db tags differ")
+}
+
+func TestRowKey(t *testing.T) {
+	assert.NotEqual(t, rowKey([]interface{}{"a,b", "c"}), rowKey([]interface{}{"a", "b,c"}))
+	assert.NotEqual(t, rowKey([]interface{}{"1:a"}), rowKey([]interface{}{"1", "a"}))
+	assert.Equal(t, rowKey([]interface{}{1, "a"}), rowKey([]interface{}{int64(1), "a"}))
+}
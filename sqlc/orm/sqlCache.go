@@ -0,0 +1,260 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/logc"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+	"go.uber.org/zap"
+)
+
+const DefaultSQLCachePurgeInterval = 5 * time.Minute
+
+var _ cachec.Cache = &SQLCache{}
+
+// CacheEntry is a row of the SQLCache table. Values are stored base64 encoded since the orm binds arguments
+// through json, ExpiresAt is in unix milliseconds and 0 never expires.
+type CacheEntry struct {
+	Key       string `json:"cache_key" db:"cache_key" qc:"primary;data_type::varchar(512)"`
+	Group     string `json:"cache_group" db:"cache_group" qc:"update"`
+	Value     string `json:"value" db:"value" qc:"update;data_type::mediumtext"`
+	ExpiresAt int64  `json:"expires_at" db:"expires_at" qc:"update;data_type::bigint"`
+}
+
+func (e *CacheEntry) expired(now time.Time) bool {
+	return e.ExpiresAt > 0 && e.ExpiresAt <= now.UnixMilli()
+}
+
+// SQLCache stores cache entries in a database table, it is meant as a shared tier, typically the L2 of a
+// TieredCache, for deployments without Redis. Expired entries are missed and deleted on read, StartPurge
+// removes the ones that are never read again.
+type SQLCache struct {
+	table           *Table[CacheEntry]
+	db              db.DB
+	defaultDuration time.Duration
+	interceptors    []cachec.Interceptor
+	mutex           *sync.Mutex
+	stopPurge       context.CancelFunc
+}
+
+// NewSQLCache creates the cache_entry table in dataset, the suffix is appended to the table name like InitializeTable.
+func NewSQLCache(ctx context.Context, d db.DB, dataset string, defaultDuration time.Duration, suffix ...string) (*SQLCache, error) {
+	table, err := NewTable[CacheEntry](dataset, QueryTypeSQL)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.InitializeTable(ctx, d, suffix...); err != nil {
+		return nil, err
+	}
+	tags := cachec.NewCacheTags("sql", table.Name)
+	return &SQLCache{
+		table:           table,
+		db:              d,
+		defaultDuration: defaultDuration,
+		interceptors:    []cachec.Interceptor{cachec.MetricsInterceptor(tags), cachec.TracingInterceptor(tags.CacheName)},
+		mutex:           &sync.Mutex{},
+	}, nil
+}
+
+func (c *SQLCache) Table() *Table[CacheEntry] {
+	return c.table
+}
+
+func (c *SQLCache) GetName() string {
+	return fmt.Sprintf("SQLCACHE_%s", c.table.FullTableName())
+}
+
+func (c *SQLCache) GetParentCaches() map[string]cachec.Cache {
+	return map[string]cachec.Cache{}
+}
+
+func (c *SQLCache) SetCache(ctx context.Context, group, key string, item interface{}) error {
+	return c.SetCacheWithExpiration(ctx, c.defaultDuration, group, key, item)
+}
+
+// SetCacheWithExpiration upserts the entry, a cacheTimeout of 0 uses the default duration and a negative one never expires.
+func (c *SQLCache) SetCacheWithExpiration(ctx context.Context, cacheTimeout time.Duration, group, key string, item interface{}) error {
+	data, err := encodeCacheItem(item)
+	if err != nil {
+		return err
+	}
+	if cacheTimeout == 0 {
+		cacheTimeout = c.defaultDuration
+	}
+	return c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdSET, Group: group, Key: key, Size: len(data)}, func(ctx context.Context, op *cachec.Operation) error {
+		_, err := c.table.Upsert(WithoutWriteNotify(ctx), c.db, CacheEntry{
+			Key:       op.Key,
+			Group:     op.Group,
+			Value:     base64.StdEncoding.EncodeToString(data),
+			ExpiresAt: expiresAt(time.Now(), cacheTimeout),
+		})
+		return err
+	})
+}
+
+func (c *SQLCache) GetCache(ctx context.Context, group, key string) ([]byte, error) {
+	var output []byte
+	err := c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdGET, Group: group, Key: key}, func(ctx context.Context, op *cachec.Operation) error {
+		entry, err := c.entry(ctx, op.Key)
+		if err != nil {
+			return err
+		}
+		output, err = base64.StdEncoding.DecodeString(entry.Value)
+		if err != nil {
+			return err
+		}
+		op.Size = len(output)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *SQLCache) DeleteKey(ctx context.Context, key string) error {
+	return c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdDELETE, Key: key}, func(ctx context.Context, op *cachec.Operation) error {
+		return c.table.Delete(WithoutWriteNotify(ctx), c.db, CacheEntry{Key: op.Key})
+	})
+}
+
+func (c *SQLCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdTTL, Key: key}, func(ctx context.Context, op *cachec.Operation) error {
+		entry, err := c.entry(ctx, op.Key)
+		if err != nil {
+			return err
+		}
+		if entry.ExpiresAt == 0 {
+			ttl = cachec.NoExpiration
+			return nil
+		}
+		ttl = time.Until(time.UnixMilli(entry.ExpiresAt))
+		return nil
+	})
+	return ttl, err
+}
+
+func (c *SQLCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.defaultDuration
+	}
+	return c.expire(ctx, key, ttl)
+}
+
+func (c *SQLCache) Persist(ctx context.Context, key string) error {
+	return c.expire(ctx, key, cachec.NoExpiration)
+}
+
+func (c *SQLCache) expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdTOUCH, Key: key}, func(ctx context.Context, op *cachec.Operation) error {
+		if _, err := c.entry(ctx, op.Key); err != nil {
+			return err
+		}
+		query := fmt.Sprintf("UPDATE %s SET expires_at = :expires_at WHERE cache_key = :cache_key", c.table.FullTableName())
+		return c.table.NamedExec(WithoutWriteNotify(ctx), c.db, query, map[string]interface{}{
+			"cache_key":  op.Key,
+			"expires_at": expiresAt(time.Now(), ttl),
+		})
+	})
+}
+
+func (c *SQLCache) Ping(ctx context.Context) error {
+	return c.db.Ping(ctx)
+}
+
+// Close stops the purge job, the db is shared with the rest of the application and stays open.
+func (c *SQLCache) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stopPurge != nil {
+		c.stopPurge()
+		c.stopPurge = nil
+	}
+}
+
+// StartPurge deletes the expired entries every interval until ctx is done or the cache is closed.
+// Reads already skip expired entries, the job only reclaims the space of keys that are not read again.
+func (c *SQLCache) StartPurge(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSQLCachePurgeInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.mutex.Lock()
+	if c.stopPurge != nil {
+		c.stopPurge()
+	}
+	c.stopPurge = cancel
+	c.mutex.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Purge(ctx); err != nil && ctx.Err() == nil {
+					logc.Error(ctx, "failed purging sql cache", zap.String("table", c.table.FullTableName()), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Purge deletes the expired entries.
+func (c *SQLCache) Purge(ctx context.Context) error {
+	return c.run(ctx, &cachec.Operation{Cmd: cachec.CacheCmdDELETE}, func(ctx context.Context, op *cachec.Operation) error {
+		query := fmt.Sprintf("DELETE FROM %s WHERE expires_at > 0 AND expires_at <= :now", c.table.FullTableName())
+		return c.table.NamedExec(WithoutWriteNotify(ctx), c.db, query, map[string]interface{}{"now": time.Now().UnixMilli()})
+	})
+}
+
+// entry returns the live entry of key, an expired entry is deleted unless it has been written again since the read.
+func (c *SQLCache) entry(ctx context.Context, key string) (*CacheEntry, error) {
+	entry, err := c.table.GetByPrimary(ctx, c.db, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, cachec.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !entry.expired(now) {
+		return entry, nil
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE cache_key = :cache_key AND expires_at > 0 AND expires_at <= :now", c.table.FullTableName())
+	_ = c.table.NamedExec(WithoutWriteNotify(ctx), c.db, query, map[string]interface{}{
+		"cache_key": key,
+		"now":       now.UnixMilli(),
+	})
+	return nil, cachec.ErrCacheMiss
+}
+
+func (c *SQLCache) run(ctx context.Context, op *cachec.Operation, call cachec.Invoker) error {
+	op.Cache = c.GetName()
+	return cachec.RunInterceptors(ctx, c.interceptors, op, call)
+}
+
+func expiresAt(now time.Time, ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return now.Add(ttl).UnixMilli()
+}
+
+// encodeCacheItem stores raw bytes as is like the cachec backends, other values are encoded as json.
+func encodeCacheItem(item interface{}) ([]byte, error) {
+	if b, ok := item.([]byte); ok {
+		return b, nil
+	}
+	return json.Marshal(item)
+}
@@ -0,0 +1,167 @@
+package orm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Seann-Moser/cutil/cachec"
+	"github.com/Seann-Moser/cutil/sqlc/orm/db"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// entryDB keeps the rows of the SQLCache table and answers its statements.
+type entryDB struct {
+	*db.MockDB
+	mutex *sync.Mutex
+	rows  map[string]CacheEntry
+}
+
+func newEntryDB() *entryDB {
+	return &entryDB{MockDB: db.NewMockDB(), mutex: &sync.Mutex{}, rows: map[string]CacheEntry{}}
+}
+
+func (e *entryDB) QueryContext(ctx context.Context, query string, args interface{}) (db.DBRow, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	a := args.(map[string]interface{})
+	if row, found := e.rows[a["cache_key"].(string)]; found {
+		return &mockRows{rows: []interface{}{row}}, nil
+	}
+	return &mockRows{}, nil
+}
+
+func (e *entryDB) ExecContext(ctx context.Context, query string, args interface{}) error {
+	if err := e.MockDB.ExecContext(ctx, query, args); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	// Table.Delete binds the row itself.
+	if row, ok := args.(CacheEntry); ok {
+		delete(e.rows, row.Key)
+		return nil
+	}
+	a := args.(map[string]interface{})
+	key, _ := a["cache_key"].(string)
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		e.rows[a["0_cache_key"].(string)] = CacheEntry{
+			Key:       a["0_cache_key"].(string),
+			Group:     a["0_cache_group"].(string),
+			Value:     a["0_value"].(string),
+			ExpiresAt: int64(a["0_expires_at"].(float64)),
+		}
+	case strings.HasPrefix(query, "UPDATE"):
+		row := e.rows[key]
+		row.ExpiresAt = int64(a["expires_at"].(float64))
+		e.rows[key] = row
+	case strings.HasPrefix(query, "DELETE"):
+		now, expiredOnly := a["now"].(float64)
+		for k, row := range e.rows {
+			if key != "" && k != key {
+				continue
+			}
+			if expiredOnly && (row.ExpiresAt == 0 || row.ExpiresAt > int64(now)) {
+				continue
+			}
+			delete(e.rows, k)
+		}
+	}
+	return nil
+}
+
+func (e *entryDB) len() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.rows)
+}
+
+func TestSQLCache(t *testing.T) {
+	ctx := context.Background()
+	d := newEntryDB()
+	c, err := NewSQLCache(ctx, d, "cache_dataset", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "SQLCACHE_cache_dataset.cache_entry", c.GetName())
+
+	_, err = c.GetCache(ctx, "group", "key")
+	assert.ErrorIs(t, err, cachec.ErrCacheMiss)
+	assert.NoError(t, c.SetCache(ctx, "group", "key", "value"))
+	v, err := c.GetCache(ctx, "group", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, `"value"`, string(v))
+	assert.Equal(t, "group", d.rows["key"].Group)
+
+	assert.NoError(t, c.SetCache(ctx, "group", "raw", []byte{0, 1, 2}))
+	v, err = c.GetCache(ctx, "group", "raw")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, v)
+
+	ttl, err := c.TTL(ctx, "key")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	assert.NoError(t, c.Persist(ctx, "key"))
+	ttl, err = c.TTL(ctx, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, cachec.NoExpiration, ttl)
+	assert.NoError(t, c.Touch(ctx, "key", time.Hour))
+	ttl, err = c.TTL(ctx, "key")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+	assert.ErrorIs(t, c.Touch(ctx, "missing", time.Hour), cachec.ErrCacheMiss)
+
+	assert.NoError(t, c.DeleteKey(ctx, "key"))
+	_, err = c.GetCache(ctx, "group", "key")
+	assert.ErrorIs(t, err, cachec.ErrCacheMiss)
+}
+
+func TestSQLCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	d := newEntryDB()
+	c, err := NewSQLCache(ctx, d, "cache_dataset", time.Minute)
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.SetCacheWithExpiration(ctx, 10*time.Millisecond, "group", "read", "value"))
+	assert.NoError(t, c.SetCacheWithExpiration(ctx, 10*time.Millisecond, "group", "unread", "value"))
+	assert.NoError(t, c.SetCacheWithExpiration(ctx, -1, "group", "forever", "value"))
+	time.Sleep(20 * time.Millisecond)
+
+	_, err = c.GetCache(ctx, "group", "read")
+	assert.ErrorIs(t, err, cachec.ErrCacheMiss)
+	assert.Equal(t, 2, d.len())
+
+	c.StartPurge(ctx, 5*time.Millisecond)
+	defer c.Close()
+	assert.Eventually(t, func() bool {
+		return d.len() == 1
+	}, time.Second, 5*time.Millisecond)
+	_, err = c.GetCache(ctx, "group", "forever")
+	assert.NoError(t, err)
+}
+
+func TestSQLCache_TieredL2(t *testing.T) {
+	cachec.GlobalCacheMonitor = cachec.NewMonitor()
+	ctx := context.Background()
+	d := newEntryDB()
+	l2, err := NewSQLCache(ctx, d, "cache_dataset", time.Minute)
+	assert.NoError(t, err)
+	newL1 := func() cachec.Cache {
+		return cachec.NewGoCache(cache.New(time.Minute, time.Minute), time.Minute, "")
+	}
+
+	// the monitor records go through the tiers too, the table writes must not invalidate the cache again.
+	writer := cachec.ContextWithCache(ctx, cachec.NewTieredCache(nil, newL1(), l2))
+	assert.NoError(t, cachec.Set[string](writer, "roles", "admin", "value"))
+
+	l1 := newL1()
+	reader := cachec.ContextWithCache(ctx, cachec.NewTieredCache(nil, l1, l2))
+	v, err := cachec.Get[string](reader, "roles", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", *v)
+
+	_, err = l1.GetCache(ctx, "roles", cachec.GetKey[string]("roles", "admin"))
+	assert.NoError(t, err, "the L2 hit backfills the L1")
+}
@@ -13,8 +13,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/Seann-Moser/cutil/cachec"
 	"go.opentelemetry.io/otel"
 
 	"github.com/google/uuid"
@@ -32,7 +32,6 @@ const (
 	TagColumnNamePrefix = "db"
 )
 
-// todo clear cache for tables when crud operation happens
 var (
 	NoOverlappingColumnsErr = errors.New("error: no overlapping columns found")
 	matchFirstCap           = regexp.MustCompile("(.)([A-Z][a-z]+)")
@@ -45,6 +44,9 @@ type Table[T any] struct {
 	Columns   map[string]db.Column `json:"columns"`
 	QueryType QueryType            `json:"query_type"`
 	db        db.DB
+
+	rowCache    bool
+	rowCacheTTL time.Duration
 }
 
 func NewTable[T any](databaseName string, queryType QueryType) (*Table[T], error) {
@@ -85,6 +87,9 @@ func NewTable[T any](databaseName string, queryType QueryType) (*Table[T], error
 		if column.Primary {
 			setPrimary = true
 		}
+		if column.RowCache {
+			newTable.rowCache = true
+		}
 		if column.Name == "-" {
 			continue
 		}
@@ -93,6 +98,9 @@ func NewTable[T any](databaseName string, queryType QueryType) (*Table[T], error
 	if !setPrimary {
 		return nil, db.MissingPrimaryKeyErr
 	}
+	if newTable.rowCache {
+		rowCacheTables.register(newTable.FullTableName(), &newTable)
+	}
 	return &newTable, nil
 }
 
@@ -442,6 +450,10 @@ func (t *Table[T]) UpdateStatement() string {
 }
 
 func DeleteStatement(fullTableName string, columns map[string]db.Column) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", fullTableName, deleteWhere(columns))
+}
+
+func deleteWhere(columns map[string]db.Column) string {
 	var whereValues []string
 	for _, e := range columns {
 		if e.Primary {
@@ -449,10 +461,10 @@ func DeleteStatement(fullTableName string, columns map[string]db.Column) string
 			continue
 		}
 		if e.Delete {
-			return fmt.Sprintf("DELETE FROM %s WHERE %s = :%s", fullTableName, e.Name, e.Name)
+			return fmt.Sprintf("%s = :%s", e.Name, e.Name)
 		}
 	}
-	return fmt.Sprintf("DELETE FROM %s WHERE %s", fullTableName, strings.Join(whereValues, " AND "))
+	return strings.Join(whereValues, " AND ")
 }
 
 func (t *Table[T]) DeleteWithColumns(ctx context.Context, fullTableName string, columns map[string]db.Column, s T) error {
@@ -465,8 +477,25 @@ func (t *Table[T]) DeleteWithColumns(ctx context.Context, fullTableName string,
 	tracer := otel.GetTracerProvider()
 	ctx, span := tracer.Tracer("delete-w-column").Start(ctx, t.FullTableName())
 	defer span.End()
-	//tableUpdateSignal <- t.FullTableName()
-	return t.db.ExecContext(ctx, DeleteStatement(fullTableName, columns), s)
+	where := deleteWhere(columns)
+	// cached rows are dropped by primary key, the keys of the matching rows are read before they are gone.
+	invalidate := func(ctx context.Context) {}
+	if rows, found := rowCacheTables.get(fullTableName); found && !writeNotifySkipped(ctx) {
+		var err error
+		invalidate, err = rows.matchingRows(ctx, t.db, where, s)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+	err := t.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", fullTableName, where), s)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	invalidate(ctx)
+	_ = NotifyWrite(ctx, fullTableName, "delete")
+	return nil
 }
 
 func (t *Table[T]) DeleteStatement() string {
@@ -642,7 +671,7 @@ func (t *Table[T]) Insert(ctx context.Context, d db.DB, s ...T) (string, error)
 		err := d.ExecContext(ctx, t.InsertStatement(len(s)), args)
 		if err == nil {
 			span.RecordError(err)
-			_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+			t.notifyWrite(ctx, "insert", s...)
 		}
 		return generateIds[t.GetGenerateID()[0].Name], err
 	}
@@ -655,7 +684,7 @@ func (t *Table[T]) Insert(ctx context.Context, d db.DB, s ...T) (string, error)
 	err = d.ExecContext(ctx, t.InsertStatement(len(s)), args)
 	if err == nil {
 		span.RecordError(err)
-		_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+		t.notifyWrite(ctx, "insert", s...)
 	}
 	return "", err
 }
@@ -687,7 +716,7 @@ func (t *Table[T]) Upsert(ctx context.Context, d db.DB, s ...T) (string, error)
 		err := d.ExecContext(ctx, t.UpsertStatement(len(s)), args)
 		if err == nil {
 			span.RecordError(err)
-			_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+			t.notifyWrite(ctx, "upsert", s...)
 		}
 		return generateIds[t.GetGenerateID()[0].Name], err
 	}
@@ -698,7 +727,7 @@ func (t *Table[T]) Upsert(ctx context.Context, d db.DB, s ...T) (string, error)
 	err = d.ExecContext(ctx, t.UpsertStatement(len(s)), args)
 	if err == nil {
 		span.RecordError(err)
-		_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+		t.notifyWrite(ctx, "upsert", s...)
 	}
 	return "", err
 }
@@ -714,9 +743,15 @@ func (t *Table[T]) InsertTx(ctx context.Context, db *sqlx.Tx, s ...T) (sql.Resul
 			return nil, "", err
 		}
 		results, err := db.NamedExecContext(ctx, t.InsertStatement(len(s)), args)
+		if err == nil {
+			t.notifyTxWrite(ctx, "insert", s...)
+		}
 		return results, generateIds[t.GetGenerateID()[0].Name], err
 	}
 	results, err := db.NamedExecContext(ctx, t.InsertStatement(len(s)), s)
+	if err == nil {
+		t.notifyTxWrite(ctx, "insert", s...)
+	}
 	return results, "", err
 }
 
@@ -735,7 +770,7 @@ func (t *Table[T]) Delete(ctx context.Context, d db.DB, s T) error {
 		span.RecordError(err)
 		return err
 	}
-	_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+	t.notifyWrite(ctx, "delete", s)
 	return nil
 }
 
@@ -747,7 +782,7 @@ func (t *Table[T]) DeleteTx(ctx context.Context, db *sqlx.Tx, s T) (sql.Result,
 	if err != nil {
 		return r, err
 	}
-	_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+	t.notifyTxWrite(ctx, "delete", s)
 	return r, nil
 }
 
@@ -766,7 +801,7 @@ func (t *Table[T]) Update(ctx context.Context, d db.DB, s T) error {
 		span.RecordError(err)
 		return err
 	}
-	_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+	t.notifyWrite(ctx, "update", s)
 	return nil
 }
 
@@ -779,7 +814,7 @@ func (t *Table[T]) UpdateTx(ctx context.Context, db *sqlx.Tx, s T) (sql.Result,
 	if err != nil {
 		return nil, err
 	}
-	_ = cachec.GlobalCacheMonitor.UpdateCache(ctx, t.FullTableName(), "insert")
+	t.notifyTxWrite(ctx, "update", s)
 	return r, nil
 }
 
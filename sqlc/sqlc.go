@@ -88,12 +88,25 @@ func ListCtx[T any](ctx context.Context, stmt ...*orm.WhereStmt) ([]*T, error) {
 	return q.Run(ctx, nil)
 }
 
+// GetIDCtx returns the row by id, it is read through the row cache when the table has it enabled.
 func GetIDCtx[T any](ctx context.Context, id string) (*T, error) {
+	if table, err := GetTableCtx[T](ctx); err == nil && table.RowCacheEnabled() && len(table.GetPrimary()) == 1 && table.GetPrimary()[0].Name == "id" {
+		return table.GetByPrimary(ctx, nil, id)
+	}
 	q := GetQuery[T](ctx)
 	q.Where(q.Column("id"), "=", "AND", 0, id)
 	return q.RunSingle(ctx, nil)
 }
 
+// GetPrimaryCtx returns the row by its primary key values in struct order.
+func GetPrimaryCtx[T any](ctx context.Context, values ...interface{}) (*T, error) {
+	table, err := GetTableCtx[T](ctx)
+	if err != nil {
+		return nil, err
+	}
+	return table.GetByPrimary(ctx, nil, values...)
+}
+
 func GetColumn[T any](ctx context.Context, name string) db.Column {
 	return GetQuery[T](ctx).Column(name)
 }